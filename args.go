@@ -0,0 +1,74 @@
+package lflag
+
+// Arg defines a required positional command line argument, returning a pointer
+// which will be filled when Parse is called. Positional arguments are filled in
+// the order they're defined, and a required one can't be defined after an
+// optional or variadic one. See NewSourceCLI.
+func (s *Set) Arg(name, usage string, opts ...ParamOption) *string {
+	p := Param{
+		ParamType:  ParamTypeString,
+		Name:       name,
+		Usage:      usage,
+		Required:   true,
+		Positional: positionalNext,
+	}
+	ptr := new(string)
+	return s.newParam(p, ptr, opts...).(*string)
+}
+
+// Arg calls Arg on CommandLine
+func Arg(name, usage string, opts ...ParamOption) *string {
+	return CommandLine.Arg(name, usage, opts...)
+}
+
+// OptionalArg is like Arg, but the argument may be omitted, in which case the
+// default value is used
+func (s *Set) OptionalArg(name, value, usage string, opts ...ParamOption) *string {
+	p := Param{
+		ParamType:  ParamTypeString,
+		Name:       name,
+		Default:    value,
+		Usage:      usage,
+		Positional: positionalNext,
+	}
+	ptr := new(string)
+	return s.newParam(p, ptr, opts...).(*string)
+}
+
+// OptionalArg calls OptionalArg on CommandLine
+func OptionalArg(name, value, usage string, opts ...ParamOption) *string {
+	return CommandLine.OptionalArg(name, value, usage, opts...)
+}
+
+// VariadicArgs defines a param which is filled with all positional command
+// line arguments after those used by Arg and OptionalArg. No other positional
+// argument may be defined after it.
+func (s *Set) VariadicArgs(name, usage string, opts ...ParamOption) *[]string {
+	p := Param{
+		ParamType:  ParamTypeStringSlice,
+		Name:       name,
+		Usage:      usage,
+		Positional: positionalNext,
+	}
+	ptr := new([]string)
+	return s.newParam(p, ptr, opts...).(*[]string)
+}
+
+// VariadicArgs calls VariadicArgs on CommandLine
+func VariadicArgs(name, usage string, opts ...ParamOption) *[]string {
+	return CommandLine.VariadicArgs(name, usage, opts...)
+}
+
+// Args returns the positional command line arguments which were left over
+// after Parse, i.e. those which weren't used by any param defined with Arg,
+// OptionalArg, or VariadicArgs.
+func (s *Set) Args() []string {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.args
+}
+
+// Args calls Args on CommandLine
+func Args() []string {
+	return CommandLine.Args()
+}
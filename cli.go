@@ -4,46 +4,348 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 )
 
-type sourceCLI struct{}
+type sourceCLI struct {
+	strict   bool
+	testArgs []string // used in place of os.Args[1:] in tests
+}
+
+// CLIOption is used to modify the behavior of the Source returned from
+// NewSourceCLI
+type CLIOption func(*sourceCLI)
+
+// CLIStrict causes any flag which doesn't correspond to a param to be an error,
+// rather than being ignored. The error will suggest the closest param name, if
+// there's one which is similar.
+func CLIStrict() CLIOption {
+	return func(sc *sourceCLI) {
+		sc.strict = true
+	}
+}
 
 // NewSourceCLI initializes  and returns a new Source which will pull from the
-// command line arguments at runtime. It also handles --help and --version
-// options
-func NewSourceCLI() Source {
-	return sourceCLI{}
+// command line arguments at runtime. It also handles --help, --version,
+// --print-config and --completion options. --print-config prints out the
+// effective configuration once it's been parsed, see WriteEffective.
+// --completion prints out a bash completion script, which completes flags,
+// subcommands, and the choices of params like Enum.
+//
+// Params with a short alias (see Shorthand) may also be given like -p 8080 or
+// -p8080, and boolean ones may be clustered together like -vq. A boolean param
+// may be followed by a boolean value like "true", "false", "yes", "no", "1" or
+// "0" to explicitly set it. Any other following argument is left as a
+// positional argument. NOTE this changed when positional arguments were added:
+// previously any following argument which didn't start with a dash was taken
+// as the value, and "--verbose=<value>" must now be used for one which isn't
+// boolean.
+//
+// List params (e.g. StringSlice) may be given multiple times, with each one
+// adding an element to the list. Map params (e.g. StringMap) are given as
+// "key=value", and may also be given multiple times.
+//
+// Flags which don't correspond to any param are ignored, unless CLIStrict is
+// used.
+//
+// Any argument which isn't a flag or a flag's value is a positional argument,
+// as is every argument after a "--" terminator. If subcommands have been defined
+// (see Command) then the first positional arguments select them. The rest fill
+// the params defined by Arg, OptionalArg and VariadicArgs in order, and any left
+// over are available from Args.
+func NewSourceCLI(opts ...CLIOption) Source {
+	var sc sourceCLI
+	for _, opt := range opts {
+		opt(&sc)
+	}
+	return sc
 }
 
 func (sc sourceCLI) Parse(pp []Param) (map[string]string, error) {
-	return parseCLI(os.Args[1:], pp)
+	vals, _, _, err := sc.parseCLI(detachedState(), sc.args(), pp)
+	return vals, err
+}
+
+func (sc sourceCLI) args() []string {
+	if sc.testArgs != nil {
+		return sc.testArgs
+	}
+	return os.Args[1:]
+}
+
+func (sc sourceCLI) parseSet(st *parseState, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	vals, origins, rest, err := sc.parseCLI(st, sc.args(), pp)
+	st.args = rest
+	if err != nil {
+		return vals, origins, sourceErrors(sc, err)
+	}
+	return vals, origins, nil
+}
+
+func (sc sourceCLI) String() string {
+	return "cli"
+}
+
+// commandPath returns the subcommands of the Set which are selected by the
+// given arguments. pp are the Set's own params, which are needed to know which
+// flags take a value.
+func (sc sourceCLI) commandPath(set *Set, args []string, pp []Param) []*Set {
+	cliM := map[string]Param{}
+	shortM := map[rune]Param{}
+	addParams := func(pp []Param) {
+		for _, p := range pp {
+			cliM["--"+p.Name] = p
+			if p.Short != 0 {
+				shortM[p.Short] = p
+			}
+		}
+	}
+	addParams(pp)
+
+	// takesValue returns whether parseCLI would use next as the value of the
+	// flag given by arg
+	takesValue := func(arg, next string) bool {
+		if arg[1] != '-' {
+			// only the last of a cluster of short aliases may take a value
+			cluster := []rune(arg[1:])
+			for i, c := range cluster {
+				if p, ok := shortM[c]; !ok {
+					return false
+				} else if p.ParamType != ParamTypeBool {
+					return i == len(cluster)-1
+				}
+			}
+			return false
+		} else if strings.Contains(arg, "=") {
+			return false
+		}
+
+		p, ok := cliM[arg]
+		if !ok {
+			return false
+		} else if p.ParamType == ParamTypeBool {
+			_, err := normalizeBool(next)
+			return err == nil
+		}
+		return true
+	}
+
+	var path []*Set
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+
+		if arg == "--" {
+			break
+		} else if arg != "-" && strings.HasPrefix(arg, "-") {
+			if len(args) > 0 && takesValue(arg, args[0]) {
+				args = args[1:]
+			}
+			continue
+		}
+
+		c, ok := set.commands[arg]
+		if !ok {
+			break
+		}
+		path, set = append(path, c), c
+
+		c.l.Lock()
+		cpp := make([]Param, 0, len(c.m))
+		for _, p := range c.m {
+			cpp = append(cpp, p.Param)
+		}
+		c.l.Unlock()
+		addParams(cpp)
+	}
+	return path
 }
 
-// split out for testing
-func parseCLI(args []string, pp []Param) (map[string]string, error) {
+// split out for testing. Returns the found values, their origins, and the
+// positional arguments which weren't used by any param. The first positional
+// arguments are skipped if they selected subcommands of the Set, see
+// commandPath. The format given to --print-config is recorded in st.
+func (sc sourceCLI) parseCLI(st *parseState, args []string, pp []Param) (map[string]string, map[string]ParamOrigin, []string, error) {
+	set := st.set
 	cliM := map[string]Param{}
+	shortM := map[rune]Param{}
+	var positionals []Param
 	for _, p := range pp {
+		if p.Positional > 0 {
+			positionals = append(positionals, p)
+			continue
+		}
 		cliM["--"+p.Name] = p
+		if p.Short != 0 {
+			shortM[p.Short] = p
+		}
 	}
 
+	sort.Slice(positionals, func(i, j int) bool {
+		return positionals[i].Positional < positionals[j].Positional
+	})
+
 	var arg string
+	var rest []string
+	var restPos []int // the position of each of rest amongst the arguments
+	pos, numArgs := 0, len(args)
+	cmds := len(set.path)
 	found := map[string]string{}
+	origins := map[string]ParamOrigin{}
+	lists := map[string][]string{}
+	maps := map[string]map[string]string{}
+	var errs Errors
+
+	unknown := func(argName string) {
+		if !sc.strict {
+			return
+		}
+		names := []string{"--help", "--version", "--print-config", "--completion"}
+		for name := range cliM {
+			names = append(names, name)
+		}
+		errs = append(errs, &Error{Err: unknownError(argName, names)})
+	}
+
+	// setOrigin records that the param was given by the current argument
+	setOrigin := func(p Param, key string) {
+		origins[p.Name] = ParamOrigin{Source: sc.String(), Key: key, Arg: pos}
+	}
+
+	setBool := func(p Param, argVal string, argValOk bool) {
+		if argValOk {
+			// e.g. "no" is normalized to "false", anything else is kept as-is
+			if b, err := normalizeBool(argVal); err == nil {
+				argVal = b
+			}
+			found[p.Name] = argVal
+		} else if p.Default == "true" {
+			found[p.Name] = ""
+		} else {
+			found[p.Name] = "true"
+		}
+	}
+
+	setVal := func(p Param, argVal string) {
+		if _, ok := listParamTypes[p.ParamType]; ok {
+			lists[p.Name] = append(lists[p.Name], argVal)
+			found[p.Name] = joinList(lists[p.Name])
+			return
+		} else if p.ParamType == ParamTypeStringMap {
+			k, v, err := splitKeyValue(argVal)
+			if err != nil {
+				errs = append(errs, &Error{Param: p.Name, Err: redactError(p, err)})
+				return
+			}
+			if maps[p.Name] == nil {
+				maps[p.Name] = map[string]string{}
+			}
+			maps[p.Name][k] = v
+			found[p.Name] = joinMap(maps[p.Name])
+			return
+		}
+		found[p.Name] = argVal
+	}
+
+	// fills the positional params from the positional arguments, in order,
+	// and returns the arguments which are left over
+	setPositionals := func(args []string, argsPos []int) []string {
+		for _, p := range positionals {
+			if len(args) == 0 {
+				break
+			}
+			origins[p.Name] = ParamOrigin{Source: sc.String(), Arg: argsPos[0]}
+			if _, ok := listParamTypes[p.ParamType]; ok {
+				found[p.Name] = joinList(args)
+				return nil
+			}
+			found[p.Name], args, argsPos = args[0], args[1:], argsPos[1:]
+		}
+		return args
+	}
+
 	for {
 		if len(args) == 0 {
-			return found, nil
+			rest = setPositionals(rest, restPos)
+			if len(errs) > 0 {
+				return found, origins, rest, errs
+			}
+			return found, origins, rest, nil
 		}
 
 		arg, args = args[0], args[1:]
+		pos = numArgs - len(args)
+
+		if arg == "--" {
+			for i := range args {
+				restPos = append(restPos, pos+1+i)
+			}
+			rest, args = append(rest, args...), nil
+			continue
+		} else if (arg == "-" || !strings.HasPrefix(arg, "-")) && cmds > 0 {
+			cmds--
+			continue
+		} else if arg == "-" || !strings.HasPrefix(arg, "-") {
+			rest, restPos = append(rest, arg), append(restPos, pos)
+			continue
+		}
 
 		argParts := strings.SplitN(arg, "=", 2)
 		argName := argParts[0]
 
+		cmd := set
+		if len(set.path) > 0 {
+			cmd = set.path[len(set.path)-1]
+		}
 		if argName == "-h" || argName == "--help" {
-			printfAndExit(cliHelpStr(pp))
+			printfAndExit(cliHelpStr(set.helpPrefix(), cmd, set.src, pp))
+		} else if argName == "--completion" {
+			printfAndExit("%s", cliCompletionStr(filepath.Base(os.Args[0]), cmd, pp))
 		} else if argName == "-V" || argName == "--version" {
 			printfAndExit(Version())
+		} else if argName == "--print-config" {
+			// the config can only be printed once it's been parsed, see ParseE
+			format := "json"
+			if len(argParts) == 2 {
+				format = argParts[1]
+			}
+			if err := checkEffectiveFormat(format); err != nil {
+				errs = append(errs, &Error{Err: fmt.Errorf("--print-config: %w", err)})
+			} else {
+				st.printConfig = format
+			}
+			continue
+		}
+
+		// short aliases may be clustered together, e.g. -vq, with the last
+		// one possibly taking a value, e.g. -vp8080 or -vp 8080
+		if len(arg) > 1 && arg[0] == '-' && arg[1] != '-' {
+			cluster := []rune(arg[1:])
+			for i, c := range cluster {
+				p, ok := shortM[c]
+				if !ok {
+					unknown("-" + string(c))
+					break
+				}
+				setOrigin(p, "-"+string(c))
+				if p.ParamType == ParamTypeBool {
+					setBool(p, "", false)
+					continue
+				}
+
+				if rest := cluster[i+1:]; len(rest) > 0 {
+					setVal(p, strings.TrimPrefix(string(rest), "="))
+				} else if len(args) > 0 {
+					setVal(p, args[0])
+					args = args[1:]
+				} else {
+					setVal(p, "")
+				}
+				break
+			}
+			continue
 		}
 
 		var argVal string
@@ -55,24 +357,20 @@ func parseCLI(args []string, pp []Param) (map[string]string, error) {
 
 		p, ok := cliM[argName]
 		if !ok {
+			unknown(argName)
 			continue
 		}
+		setOrigin(p, argName)
 
 		if p.ParamType == ParamTypeBool {
-			// check for a true/false value
+			// check for a boolean value, anything else is left as a positional
 			if !argValOk && len(args) > 0 {
-				if !strings.HasPrefix(args[0], "-") {
+				if _, err := normalizeBool(args[0]); err == nil {
 					argValOk = true
 					argVal, args = args[0], args[1:]
 				}
 			}
-			if argValOk {
-				found[p.Name] = argVal
-			} else if p.Default == "true" {
-				found[p.Name] = ""
-			} else {
-				found[p.Name] = "true"
-			}
+			setBool(p, argVal, argValOk)
 			continue
 		}
 
@@ -80,30 +378,50 @@ func parseCLI(args []string, pp []Param) (map[string]string, error) {
 			argVal, args = args[0], args[1:]
 		}
 
-		found[p.Name] = argVal
+		setVal(p, argVal)
 	}
 }
 
-// returns string form of help message. newline will be appended already
-func cliHelpStr(pp []Param) string {
+// returns string form of help message, for the given Set or subcommand of it.
+// If src includes an environment Source then the variable each param is read
+// from is shown. newline will be appended already
+func cliHelpStr(helpPrefix string, cmd *Set, src Source, pp []Param) string {
+	se, hasEnv := findSource[sourceEnv](src)
 
 	sort.Slice(pp, func(i, j int) bool {
 		return pp[i].Name < pp[j].Name
 	})
 
+	// positional params are listed first, in the order they're given
+	sort.SliceStable(pp, func(i, j int) bool {
+		return pp[i].Positional > 0 && (pp[j].Positional == 0 || pp[i].Positional < pp[j].Positional)
+	})
+
 	buf := bytes.NewBuffer(make([]byte, 0, 1024))
 	bufParam := func(p Param) {
-		fmt.Fprintf(buf, "\t--%s", p.Name)
-		if p.ParamType == ParamTypeBool {
-			fmt.Fprintf(buf, " (flag)")
-		}
-		fmt.Fprintf(buf, "\n")
+		fmt.Fprintf(buf, "\t%s\n", cliHelpName(p))
 
 		if p.Usage != "" {
 			fmt.Fprintf(buf, "\t\t%s\n", p.Usage)
 		}
 
-		if p.Default != "" {
+		if len(p.Choices) > 0 {
+			fmt.Fprintf(buf, "\t\tChoices: %s\n", strings.Join(p.Choices, ", "))
+		}
+
+		if hasEnv && p.Positional == 0 && p.Name != "help" && p.Name != "version" && p.Name != "print-config" && p.Name != "completion" {
+			name := se.envName(p.Name)
+			if se.files && fileParam(p) {
+				name += ", " + name + "_FILE"
+			}
+			fmt.Fprintf(buf, "\t\tEnvironment: %s\n", name)
+		}
+
+		if p.Secret && p.Default != "" {
+			fmt.Fprintf(buf, "\t\tDefault: %s\n", redactedValue)
+		} else if _, ok := listParamTypes[p.ParamType]; (ok || p.ParamType == ParamTypeStringMap) && p.Default != "" {
+			fmt.Fprintf(buf, "\t\tDefault: %s\n", p.Default)
+		} else if p.Default != "" {
 			fmt.Fprintf(buf, "\t\tDefault: %q\n", p.Default)
 		} else if p.Required {
 			fmt.Fprintf(buf, "\t\t(Required)\n")
@@ -116,28 +434,83 @@ func cliHelpStr(pp []Param) string {
 		fmt.Fprintf(buf, "\n")
 	}
 
-	if HelpPrefix != "" {
-		fmt.Fprintf(buf, "\n%s", HelpPrefix)
-		if HelpPrefix[len(HelpPrefix)-1] != '\n' {
+	if helpPrefix != "" {
+		fmt.Fprintf(buf, "\n%s", helpPrefix)
+		if helpPrefix[len(helpPrefix)-1] != '\n' {
 			// ensure we always write at least one newline
 			fmt.Fprint(buf, "\n")
 		}
 	}
 
+	if cmd.parent != nil {
+		fmt.Fprintf(buf, "\n%s", cmd.commandName())
+		if cmd.usage != "" {
+			fmt.Fprintf(buf, ": %s", cmd.usage)
+		}
+		fmt.Fprint(buf, "\n")
+	}
+
 	fmt.Fprint(buf, "\n")
+	for _, name := range cmd.commandNames() {
+		fmt.Fprintf(buf, "\t%s (command)\n", name)
+		if usage := cmd.commands[name].usage; usage != "" {
+			fmt.Fprintf(buf, "\t\t%s\n", usage)
+		}
+		fmt.Fprint(buf, "\n")
+	}
 	for _, p := range pp {
 		bufParam(p)
 	}
 	bufParam(Param{
 		ParamType: ParamTypeBool,
 		Name:      "help",
+		Short:     'h',
 		Usage:     "Show this help message and exit",
 	})
 	bufParam(Param{
 		ParamType: ParamTypeBool,
 		Name:      "version",
+		Short:     'V',
 		Usage:     "Print out a build string and exit",
 	})
+	bufParam(Param{
+		ParamType: ParamTypeBool,
+		Name:      "print-config",
+		Usage: "Print out the effective configuration, as json, and exit. " +
+			"--print-config=yaml and --print-config=table may be used for other formats",
+	})
+	bufParam(Param{
+		ParamType: ParamTypeBool,
+		Name:      "completion",
+		Usage:     "Print out a bash completion script and exit, e.g. for use with: source <(prog --completion)",
+	})
 
 	return buf.String()
 }
+
+// cliHelpName returns how the param is shown in the help message, e.g.
+// "--port, -p" or "<file>"
+func cliHelpName(p Param) string {
+	_, isList := listParamTypes[p.ParamType]
+	if p.Positional > 0 {
+		if isList {
+			return "[" + p.Name + "...]"
+		} else if p.Required {
+			return "<" + p.Name + ">"
+		}
+		return "[" + p.Name + "]"
+	}
+
+	name := "--" + p.Name
+	if p.Short != 0 {
+		name += ", -" + string(p.Short)
+	}
+	if p.ParamType == ParamTypeBool {
+		name += " (flag)"
+	} else if isList {
+		name += " (repeatable)"
+	} else if p.ParamType == ParamTypeStringMap {
+		name += " key=value (repeatable)"
+	}
+	return name
+}
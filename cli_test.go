@@ -1,6 +1,7 @@
 package lflag
 
 import (
+	"errors"
 	. "testing"
 
 	"github.com/stretchr/testify/assert"
@@ -8,15 +9,16 @@ import (
 )
 
 func TestCLI(t *T) {
-	found, err := parseCLI([]string{
+	found, _, rest, err := sourceCLI{}.parseCLI(detachedState(), []string{
 		"--foo", "bats", "--bar=butts", "--flag1",
 		"--flag2", "false",
-		"something",         // should be ignored
+		"something",         // should be left over
 		"something=else",    // so should this
 		"something", "else", // and these
-		"--unk=wat", // and whatever this is
+		"--unk=wat", // and whatever this is should be ignored
 	}, testParams)
 	require.Nil(t, err)
+	assert.Equal(t, []string{"something", "something=else", "something", "else"}, rest)
 	assert.Equal(t,
 		map[string]string{
 			"foo":   "bats",
@@ -27,3 +29,122 @@ func TestCLI(t *T) {
 		found,
 	)
 }
+
+func TestCLIList(t *T) {
+	pp := []Param{
+		{ParamType: ParamTypeStringSlice, Name: "peer"},
+		{ParamType: ParamTypeDurationSlice, Name: "dur"},
+	}
+	found, _, _, err := sourceCLI{}.parseCLI(detachedState(), []string{
+		"--peer", "a", "--dur=1s", "--peer=b", "--peer", "c",
+	}, pp)
+	require.Nil(t, err)
+	assert.Equal(t,
+		map[string]string{
+			"peer": `["a","b","c"]`,
+			"dur":  `["1s"]`,
+		},
+		found,
+	)
+}
+
+func TestCLIMap(t *T) {
+	pp := []Param{
+		{ParamType: ParamTypeStringMap, Name: "label"},
+	}
+	found, _, _, err := sourceCLI{}.parseCLI(detachedState(), []string{
+		"--label", "env=prod", "--label=team=infra",
+	}, pp)
+	require.Nil(t, err)
+	assert.Equal(t,
+		map[string]string{"label": `{"env":"prod","team":"infra"}`},
+		found,
+	)
+
+	_, _, _, err = sourceCLI{}.parseCLI(detachedState(), []string{"--label", "env"}, pp)
+	assert.EqualError(t, err, `parameter "label": malformed key=value pair "env"`)
+}
+
+func TestCLIShort(t *T) {
+	pp := []Param{
+		{ParamType: ParamTypeBool, Name: "verbose", Short: 'v'},
+		{ParamType: ParamTypeBool, Name: "quiet", Short: 'q'},
+		{ParamType: ParamTypeString, Name: "port", Short: 'p'},
+		{ParamType: ParamTypeStringSlice, Name: "peer", Short: 'P'},
+	}
+	found, _, _, err := sourceCLI{}.parseCLI(detachedState(), []string{
+		"-vq", "-P", "a", "-Pb", "-x", "-p8080",
+	}, pp)
+	require.Nil(t, err)
+	assert.Equal(t,
+		map[string]string{
+			"verbose": "true",
+			"quiet":   "true",
+			"port":    "8080",
+			"peer":    `["a","b"]`,
+		},
+		found,
+	)
+
+	found, _, _, err = sourceCLI{}.parseCLI(detachedState(), []string{"-qp", "8080"}, pp)
+	require.Nil(t, err)
+	assert.Equal(t,
+		map[string]string{"quiet": "true", "port": "8080"},
+		found,
+	)
+}
+
+func TestCLIPositional(t *T) {
+	pp := []Param{
+		{ParamType: ParamTypeBool, Name: "verbose", Short: 'v'},
+		{ParamType: ParamTypeString, Name: "in", Positional: 1, Required: true},
+		{ParamType: ParamTypeString, Name: "out", Positional: 2},
+		{ParamType: ParamTypeStringSlice, Name: "rest", Positional: 3},
+	}
+	found, _, rest, err := sourceCLI{}.parseCLI(detachedState(), []string{
+		"a", "--verbose", "b", "--", "-c", "--d",
+	}, pp)
+	require.Nil(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t,
+		map[string]string{
+			"verbose": "true",
+			"in":      "a",
+			"out":     "b",
+			"rest":    `["-c","--d"]`,
+		},
+		found,
+	)
+
+	found, _, rest, err = sourceCLI{}.parseCLI(detachedState(), []string{"-", "-v"}, pp[:3])
+	require.Nil(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, map[string]string{"verbose": "true", "in": "-"}, found)
+
+	// only boolean values are taken by a bool flag
+	found, _, rest, err = sourceCLI{}.parseCLI(detachedState(), []string{"--verbose", "no", "--verbose", "yes", "a"}, pp[:2])
+	require.Nil(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, map[string]string{"verbose": "true", "in": "a"}, found)
+
+	found, _, rest, err = sourceCLI{}.parseCLI(detachedState(), []string{"--verbose", "Off", "a"}, pp[:2])
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"verbose": "false", "in": "a"}, found)
+
+	found, _, rest, err = sourceCLI{}.parseCLI(detachedState(), []string{"a", "b"}, pp[:1])
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, rest)
+	assert.Empty(t, found)
+}
+
+func TestCLIStrict(t *T) {
+	sc := NewSourceCLI(CLIStrict()).(sourceCLI)
+	_, _, _, err := sc.parseCLI(detachedState(), []string{
+		"--foo", "bats", "--fo-bar=baz", "--wat", "-x", "positional",
+	}, testParams)
+	assert.EqualError(t, err, `3 configuration errors:
+	unknown parameter "--fo-bar", did you mean "--foo-bar"?
+	unknown parameter "--wat"
+	unknown parameter "-x"`)
+	assert.True(t, errors.Is(err, ErrUnknown))
+}
@@ -0,0 +1,141 @@
+package lflag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Command defines a subcommand of the Set, e.g. the "up" in "migrate up", and
+// returns a new Set on which the subcommand's own params, Do functions, and
+// further subcommands may be defined.
+//
+// When parsing the Set the first positional command line argument (see
+// NewSourceCLI) selects which of its subcommands is used, the next selects a
+// subcommand of that one, and so on. The params of the Set and of every
+// selected subcommand are filled in, while those of other subcommands are left
+// untouched. After the Set's Do functions have been called those of each
+// selected subcommand are called in turn, and lastly run is called, if it was
+// given, for the final selected subcommand.
+//
+// If a Set or selected subcommand has subcommands defined on it, but its own run
+// function is nil, then one of its subcommands must be selected or ParseE will
+// return an error wrapping ErrCommandRequired.
+func (s *Set) Command(name, usage string, run func()) *Set {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if name == "" || strings.HasPrefix(name, "-") {
+		panic(fmt.Sprintf("invalid command name %q", name))
+	} else if _, ok := s.commands[name]; ok {
+		panic(fmt.Sprintf("command named %q already exists", name))
+	}
+
+	c := NewSet()
+	c.name, c.usage, c.run, c.parent = name, usage, run, s
+	if s.commands == nil {
+		s.commands = map[string]*Set{}
+	}
+	s.commands[name] = c
+	return c
+}
+
+// Command calls Command on CommandLine
+func Command(name, usage string, run func()) *Set {
+	return CommandLine.Command(name, usage, run)
+}
+
+// CommandPath returns the names of the subcommands which were selected by the
+// last Parse, e.g. ["migrate", "up"], or nil if none were.
+func (s *Set) CommandPath() []string {
+	s.l.Lock()
+	defer s.l.Unlock()
+	var names []string
+	for _, c := range s.path {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// CommandPath calls CommandPath on CommandLine
+func CommandPath() []string {
+	return CommandLine.CommandPath()
+}
+
+// commandName returns the full name of the command, e.g. "migrate up"
+func (s *Set) commandName() string {
+	if s.parent == nil || s.parent.parent == nil {
+		return s.name
+	}
+	return s.parent.commandName() + " " + s.name
+}
+
+// commandNames returns the sorted names of the Set's subcommands
+func (s *Set) commandNames() []string {
+	names := make([]string, 0, len(s.commands))
+	for name := range s.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolvePath returns the subcommands selected by the command line arguments of
+// the given Source. s.l must be held.
+func (s *Set) resolvePath(src Source) []*Set {
+	if len(s.commands) == 0 {
+		return nil
+	}
+	sc, ok := findSource[sourceCLI](src)
+	if !ok {
+		return nil
+	}
+
+	pp := make([]Param, 0, len(s.m))
+	for _, p := range s.m {
+		pp = append(pp, p.Param)
+	}
+	return sc.commandPath(s, sc.args(), pp)
+}
+
+// pathParams returns the params of the Set and of each of the given
+// subcommands, keyed by name. The positional params of each subcommand follow
+// on from those of the one before it. s.l, and that of each subcommand, must be
+// held.
+func (s *Set) pathParams(path []*Set) (map[string]param, Errors) {
+	m := make(map[string]param, len(s.m))
+	shorts := map[rune]string{}
+	var errs Errors
+	var offset int
+	for _, set := range append([]*Set{s}, path...) {
+		var last int
+		for name, p := range set.m {
+			if _, ok := m[name]; ok {
+				errs = append(errs, &Error{
+					Param: name,
+					Err:   fmt.Errorf("redefined by command %q", set.commandName()),
+				})
+				continue
+			} else if other, ok := shorts[p.Short]; ok && p.Short != 0 {
+				errs = append(errs, &Error{
+					Param: name,
+					Err:   fmt.Errorf("has the same short alias -%c as %q", p.Short, other),
+				})
+				continue
+			}
+
+			if p.Positional > 0 {
+				if p.Positional > last {
+					last = p.Positional
+				}
+				p.Positional += offset
+			}
+			m[name] = p
+			if p.Short != 0 {
+				shorts[p.Short] = name
+			}
+		}
+		offset += last
+	}
+	return m, errs
+}
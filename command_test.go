@@ -0,0 +1,114 @@
+package lflag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *T) {
+	newSet := func() (*Set, *[]string) {
+		var calls []string
+		s := NewSet()
+		verbose := s.Bool("verbose", false, "Verbose output", Shorthand('v'))
+		s.Do(func() { calls = append(calls, "root") })
+
+		up := s.Command("up", "Apply migrations", func() {
+			calls = append(calls, "up run")
+		})
+		steps := up.Int("steps", 0, "Number of migrations to apply", Shorthand('n'))
+		target := up.OptionalArg("target", "", "Migration to stop at")
+		up.Do(func() {
+			calls = append(calls, "up", fmt.Sprintf(
+				"verbose=%v steps=%d target=%s", *verbose, *steps, *target,
+			))
+		})
+
+		down := s.Command("down", "Roll back migrations", nil)
+		down.Int("steps", 1, "Number of migrations to roll back")
+		down.Command("all", "Roll back everything", func() {
+			calls = append(calls, "down all run")
+		})
+		return s, &calls
+	}
+
+	s, calls := newSet()
+	err := s.ParseE(sourceCLI{testArgs: []string{"-v", "up", "-n", "3", "005", "extra"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up"}, s.CommandPath())
+	assert.Equal(t, []string{"extra"}, s.Args())
+	assert.Equal(t, []string{
+		"root", "up", "verbose=true steps=3 target=005", "up run",
+	}, *calls)
+
+	// a bool flag's value isn't taken as a command
+	s, calls = newSet()
+	err = s.ParseE(sourceCLI{testArgs: []string{"--verbose", "no", "up"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up"}, s.CommandPath())
+	assert.Equal(t, []string{
+		"root", "up", "verbose=false steps=0 target=", "up run",
+	}, *calls)
+
+	s, calls = newSet()
+	err = s.ParseE(sourceCLI{testArgs: []string{"down", "--steps", "2", "all"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"down", "all"}, s.CommandPath())
+	assert.Equal(t, []string{"root", "down all run"}, *calls)
+
+	s, calls = newSet()
+	err = s.ParseE(sourceCLI{testArgs: []string{"down"}})
+	assert.True(t, errors.Is(err, ErrCommandRequired))
+	assert.EqualError(t, err, "a command is required, one of: all")
+	assert.Empty(t, *calls)
+
+	s, _ = newSet()
+	err = s.ParseE(Sources{SourceStub{}, sourceCLI{testArgs: []string{"--", "up"}}})
+	assert.EqualError(t, err, "a command is required, one of: down, up")
+}
+
+func TestCommandRedefined(t *T) {
+	s := NewSet()
+	s.String("addr", "", "Some addr")
+	c := s.Command("serve", "Serve things", func() {})
+	c.String("addr", "", "Some other addr")
+	assert.Panics(t, func() {
+		s.Command("serve", "Serve things again", nil)
+	})
+
+	err := s.ParseE(sourceCLI{testArgs: []string{"serve"}})
+	assert.EqualError(t, err, `parameter "addr": redefined by command "serve"`)
+}
+
+func TestCommandHelp(t *T) {
+	s := NewSet()
+	s.Bool("verbose", false, "Verbose output")
+	c := s.Command("down", "Roll back migrations", nil)
+	c.Command("all", "Roll back everything", func() {})
+
+	help := cliHelpStr("", s, nil, []Param{
+		{ParamType: ParamTypeBool, Name: "verbose", Usage: "Verbose output"},
+	})
+	assert.True(t, strings.HasPrefix(help, `
+	down (command)
+		Roll back migrations
+
+	--verbose (flag)
+		Verbose output
+		(Optional)
+
+`), help)
+
+	help = cliHelpStr("", c, nil, nil)
+	assert.True(t, strings.HasPrefix(help, `
+down: Roll back migrations
+
+	all (command)
+		Roll back everything
+
+`), help)
+}
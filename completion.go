@@ -0,0 +1,61 @@
+package lflag
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var nonWordRegexp = regexp.MustCompile(`\W`)
+
+// cliCompletionStr returns a bash completion script for the program, as printed
+// by --completion. It completes the flags of the given params and the
+// subcommands of cmd, and the choices of a param (see Enum) after its flag.
+func cliCompletionStr(prog string, cmd *Set, pp []Param) string {
+	words := cmd.commandNames()
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
+	for _, p := range pp {
+		if p.Positional > 0 {
+			continue
+		}
+		flags := []string{"--" + p.Name}
+		if p.Short != 0 {
+			flags = append(flags, "-"+string(p.Short))
+		}
+		words = append(words, flags[0])
+		if len(p.Choices) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "\t%s)\n", strings.Join(flags, "|"))
+		// compgen expands the words it's given, so they're escaped for that too
+		choices := make([]string, len(p.Choices))
+		for i, c := range p.Choices {
+			choices[i] = nonWordRegexp.ReplaceAllString(c, `\$0`)
+		}
+		fmt.Fprintf(buf, "\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(choices, " ")))
+		fmt.Fprint(buf, "\t\treturn\n\t\t;;\n")
+	}
+	words = append(words, "--help", "--version", "--print-config", "--completion")
+
+	fn := "_lflag_" + nonWordRegexp.ReplaceAllString(prog, "_")
+	return fmt.Sprintf(`%s() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+	# bash splits --flag=value into "--flag", "=" and "value"
+	if [[ $cur == = ]]; then
+		cur=
+	elif [[ $prev == = ]]; then
+		prev=${COMP_WORDS[COMP_CWORD-2]}
+	fi
+	case $prev in
+%s	esac
+	COMPREPLY=($(compgen -W %s -- "$cur"))
+}
+complete -F %s %s
+`, fn, buf.String(), shellQuote(strings.Join(words, " ")), fn, shellQuote(prog))
+}
+
+// shellQuote quotes str for use as a single word in a shell script
+func shellQuote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+}
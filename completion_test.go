@@ -0,0 +1,46 @@
+package lflag
+
+import (
+	"os/exec"
+	"strings"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletion(t *T) {
+	s := NewSet()
+	s.Enum("log-level", "info", []string{"debug", "info", "it's"}, "Log level", Shorthand('l'))
+	s.Int("port", 0, "Port")
+	s.Arg("target", "Target")
+	s.Command("up", "Apply migrations", nil)
+	pp := []Param{s.m["log-level"].Param, s.m["port"].Param, s.m["target"].Param}
+
+	script := cliCompletionStr("my-app", s, pp)
+	assert.Contains(t, script, "\t--log-level|-l)\n")
+	assert.Contains(t, script, "compgen -W 'up --log-level --port --help --version --print-config --completion'")
+	assert.NotContains(t, script, "target")
+	assert.True(t, strings.HasSuffix(script, "complete -F _lflag_my_app 'my-app'\n"))
+	assert.Contains(t, cliHelpStr("", s, nil, nil), "\t--completion (flag)\n")
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash isn't available to run the script")
+	}
+	// complete returns what the script completes the last of the words to
+	complete := func(words ...string) string {
+		args := append([]string{"-c", script + `
+			COMP_WORDS=("$@"); COMP_CWORD=$(($#-1)); _lflag_my_app
+			echo "${COMPREPLY[*]}"`, "bash", "my-app"}, words...)
+		out, err := exec.Command(bash, args...).Output()
+		require.NoError(t, err)
+		return strings.TrimSpace(string(out))
+	}
+	assert.Equal(t, "up", complete("u"))
+	assert.Equal(t, "--port --print-config", complete("--p"))
+	assert.Equal(t, "debug info it's", complete("--log-level", ""))
+	assert.Equal(t, "info it's", complete("-l", "i"))
+	assert.Equal(t, "debug info it's", complete("--log-level", "=", ""))
+	assert.Equal(t, "debug", complete("--log-level", "=", "d"))
+}
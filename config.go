@@ -0,0 +1,294 @@
+package lflag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// includeKey is the key in a config file which gives the paths of other config
+// files to include, see configFile
+const includeKey = "$include"
+
+// configFileFunc decodes a config file, returning the values found in it for
+// the given params, the key each was found under (see flattenConfig), and the
+// paths of any files it includes
+type configFileFunc func(io.Reader, []Param) (vals, keys map[string]string, includes []string, err error)
+
+// configFile implements parseSet for Sources like NewSourceJSON which wrap an
+// inner Source and read values out of config files.
+//
+// The names of the files are given by a repeatable param, which is added to the
+// params given to the inner Source. Each file is read in order with later ones
+// overwriting earlier ones, and the values from the inner Source overwrite
+// them all. A name may also be that of a directory, in which case all the files
+// in it with one of the extensions are read in lexical order.
+//
+// A file may include other files by giving their paths, relative to its own
+// directory, under includeKey. Those are read before the file itself so that
+// it overwrites them.
+type configFile struct {
+	st         *parseState
+	src, inner Source
+	name, kind string   // the name of the param giving the files, e.g. "config-json-file"
+	exts       []string // the extensions of config files within a directory
+	testFile   io.Reader
+	parse      configFileFunc
+}
+
+// configFileUsage returns the Usage of the param giving the names of config files
+// of the given kind, e.g. "json"
+func configFileUsage(kind string) string {
+	return fmt.Sprintf(
+		"Name of %s file, or directory of %s files, to parse config object out of. "+
+			"May be given multiple times, with later files overwriting earlier ones. "+
+			"Environment and CLI params overwrite %s ones",
+		kind, kind, kind,
+	)
+}
+
+func (cf configFile) parseSet(st *parseState, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	cf.st = st
+	pp = append(pp, Param{
+		ParamType: ParamTypeStringSlice,
+		Name:      cf.name,
+		Usage:     configFileUsage(cf.kind),
+		paths:     true,
+	})
+
+	// errors from the inner Source don't stop us from reading the config files,
+	// so that all errors can be reported at once
+	m, mOrigins, err := st.parseSource(cf.inner, pp)
+	var errs Errors
+	if err != nil {
+		errs = errs.append(err)
+	}
+
+	out := map[string]string{}
+	origins := map[string]ParamOrigin{}
+	if paths := configPaths(m[cf.name]); len(paths) > 0 {
+		for _, path := range paths {
+			errs = append(errs, cf.readPath(path, pp, out, origins, nil)...)
+		}
+	} else if cf.testFile != nil {
+		errs = append(errs, cf.read("", cf.testFile, pp, out, origins, nil)...)
+	}
+
+	// merge m into out (so the inner source values overwrite this ones') and
+	// return that
+	for k, v := range m {
+		out[k] = v
+		origins[k] = mOrigins[k]
+	}
+
+	if len(errs) > 0 {
+		return out, origins, errs
+	}
+	return out, origins, nil
+}
+
+// configPaths returns the paths given by the value of a configFile's param. The
+// value is normally a list, but one which isn't, e.g. from a custom Source, is
+// taken to be a single path.
+func configPaths(val string) []string {
+	if paths, err := splitList(val); err == nil {
+		return paths
+	}
+	return []string{val}
+}
+
+// readPath reads the config file, or directory of them, at the given path into
+// out, and the origins of the values into origins. stack holds the files which
+// included this one. The path, and that of each file read, is recorded in the
+// parseState's files, see Watch, so that files being added to a directory as well as
+// those being changed are noticed.
+func (cf configFile) readPath(path string, pp []Param, out map[string]string, origins map[string]ParamOrigin, stack []string) Errors {
+	info, err := os.Stat(path)
+	if err == nil && !info.IsDir() {
+		return cf.readFile(path, pp, out, origins, stack)
+	}
+
+	// a path which doesn't exist is still watched, in case it's created
+	if abs, err := filepath.Abs(path); err == nil {
+		cf.st.files = append(cf.st.files, abs)
+	}
+	if err != nil {
+		return sourceErrors(cf.src, err)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return sourceErrors(cf.src, err)
+	}
+	var errs Errors
+	for _, e := range entries {
+		if e.IsDir() || !cf.hasExt(e.Name()) {
+			continue
+		}
+		errs = append(errs, cf.readFile(filepath.Join(path, e.Name()), pp, out, origins, stack)...)
+	}
+	return errs
+}
+
+func (cf configFile) hasExt(name string) bool {
+	for _, ext := range cf.exts {
+		if strings.EqualFold(filepath.Ext(name), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cf configFile) readFile(path string, pp []Param, out map[string]string, origins map[string]ParamOrigin, stack []string) Errors {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return sourceErrors(cf.src, err)
+	}
+	cf.st.files = append(cf.st.files, abs)
+	for i := range stack {
+		if stack[i] == abs {
+			cycle := strings.Join(append(stack[i:], abs), " -> ")
+			return sourceErrors(cf.src, fmt.Errorf("include cycle: %s", cycle))
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return sourceErrors(cf.src, err)
+	}
+	defer f.Close()
+	return cf.read(path, f, pp, out, origins, append(stack, abs))
+}
+
+// read decodes the config file from r, reading any files it includes first, and
+// merges its values into out and their origins into origins. Errors are
+// prefixed with the path, if given.
+func (cf configFile) read(path string, r io.Reader, pp []Param, out map[string]string, origins map[string]ParamOrigin, stack []string) Errors {
+	vals, keys, includes, err := cf.parse(r, pp)
+
+	var errs Errors
+	if err != nil {
+		errs = sourceErrors(cf.src, err)
+		if path != "" {
+			for _, e := range errs {
+				e.Err = fmt.Errorf("%s: %w", path, e.Err)
+			}
+		}
+	}
+
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(path), inc)
+		}
+		errs = append(errs, cf.readPath(inc, pp, out, origins, stack)...)
+	}
+
+	for k, v := range vals {
+		out[k] = v
+		origins[k] = ParamOrigin{Source: sourceName(cf.src), Key: keys[k], File: path}
+	}
+	return errs
+}
+
+// configIncludes returns the paths given under includeKey in a config file,
+// which may be a single string or a list of them
+func configIncludes(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		paths := make([]string, len(v))
+		for i := range v {
+			var ok bool
+			if paths[i], ok = v[i].(string); !ok {
+				return nil, errors.New(includeKey + " must be a path or list of paths")
+			}
+		}
+		return paths, nil
+	}
+	return nil, errors.New(includeKey + " must be a path or list of paths")
+}
+
+// flattenConfig takes a tree of values decoded from a config file and returns
+// the string forms of the values it has for the given params. The keys within a
+// table are prefixed by the table's name and "-", like Prefixed, so "addr"
+// within "db" is the value of "db-addr". If a table's name is itself that of a
+// param, e.g. a StringMap, then the whole table is the value of that param. If
+// a param is given by more than one key, e.g. both "db-addr" and "addr" within
+// "db", then that's an error. The key each value was given by is also returned,
+// with the keys of nested tables joined by ".", e.g. "db.addr".
+//
+// toString converts a value from the tree into the string form for its param,
+// and table returns the contents of a value if it's a table, or nil if not.
+func flattenConfig(
+	tree map[string]interface{}, pp []Param,
+	toString func(Param, interface{}) (string, error),
+	table func(interface{}) map[string]interface{},
+) (
+	vals, keys map[string]string, err error,
+) {
+	pm := make(map[string]Param, len(pp))
+	for _, p := range pp {
+		if p.Positional == 0 {
+			pm[strings.ToLower(p.Name)] = p
+		}
+	}
+
+	var errs Errors
+	out := map[string]string{}
+	keys = map[string]string{}
+	var walk func(string, string, map[string]interface{})
+	walk = func(prefix, keyPrefix string, m map[string]interface{}) {
+		kk := make([]string, 0, len(m))
+		for k := range m {
+			kk = append(kk, k)
+		}
+		sort.Strings(kk)
+
+		for _, k := range kk {
+			name, key := Prefixed(prefix, k), k
+			if keyPrefix != "" {
+				key = keyPrefix + "." + k
+			}
+
+			if p, ok := pm[name]; ok {
+				if prevKey, ok := keys[p.Name]; ok {
+					errs = append(errs, &Error{
+						Param: p.Name,
+						Err:   fmt.Errorf("given by both %q and %q", prevKey, key),
+					})
+					continue
+				}
+				keys[p.Name] = key
+
+				str, err := toString(p, m[k])
+				if err != nil {
+					errs = append(errs, &Error{Param: p.Name, Err: redactError(p, err)})
+					continue
+				}
+				out[p.Name] = str
+			} else if sub := table(m[k]); sub != nil {
+				walk(name, key, sub)
+			}
+		}
+	}
+	walk("", "", tree)
+
+	if len(errs) > 0 {
+		return out, keys, errs
+	}
+	return out, keys, nil
+}
+
+// configTable is used as the table argument of flattenConfig for trees where
+// each table is a map[string]interface{}
+func configTable(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
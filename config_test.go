@@ -0,0 +1,91 @@
+package lflag
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFiles(t *T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+		return path
+	}
+
+	shared := write("shared/defaults.json", `{"a": "shared", "b": "shared", "c": "shared", "d": "shared"}`)
+	base := write("base.json", `{"$include": "shared/defaults.json", "b": "base", "c": "base"}`)
+	write("conf.d/10-first.json", `{"c": "first", "d": "first"}`)
+	write("conf.d/20-second.json", `{"d": "second"}`)
+	write("conf.d/README.md", `not json`)
+
+	pp := []Param{
+		{ParamType: ParamTypeString, Name: "a"},
+		{ParamType: ParamTypeString, Name: "b"},
+		{ParamType: ParamTypeString, Name: "c"},
+		{ParamType: ParamTypeString, Name: "d"},
+		{ParamType: ParamTypeString, Name: "e"},
+	}
+
+	sj := NewSourceJSON(sourceCLI{testArgs: []string{
+		"--config-json-file", base,
+		"--config-json-file", filepath.Join(dir, "conf.d"),
+		"--e", "cli",
+	}}).(sourceJSON)
+	st := detachedState()
+	vals, origins, err := sj.parseSet(st, pp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		base, shared, filepath.Join(dir, "conf.d"),
+		filepath.Join(dir, "conf.d", "10-first.json"),
+		filepath.Join(dir, "conf.d", "20-second.json"),
+	}, st.files)
+	assert.Equal(t, map[string]string{
+		"a":                "shared",
+		"b":                "base",
+		"c":                "first",
+		"d":                "second",
+		"e":                "cli",
+		"config-json-file": joinList([]string{base, filepath.Join(dir, "conf.d")}),
+	}, vals)
+	assert.Equal(t, ParamOrigin{Source: "json", Key: "a", File: shared}, origins["a"])
+	assert.Equal(t, ParamOrigin{
+		Source: "json", Key: "d", File: filepath.Join(dir, "conf.d", "20-second.json"),
+	}, origins["d"])
+	assert.Equal(t, ParamOrigin{Source: "cli", Key: "--e", Arg: 5}, origins["e"])
+
+	// a cycle of includes is an error
+	write("shared/defaults.json", `{"$include": ["../base.json"]}`)
+	_, _, err = sj.parseSet(detachedState(), pp)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "include cycle: "+base+" -> "+shared+" -> "+base)
+
+	// as is a missing file, and errors within a file say which file
+	bad := write("bad.json", `{"a": 1}`)
+	_, _, err = NewSourceJSON(SourceStub{
+		"config-json-file": joinList([]string{filepath.Join(dir, "missing.json"), bad}),
+	}).(sourceJSON).parseSet(detachedState(), pp)
+	require.Error(t, err)
+	errs := strings.Split(err.Error(), "\n\t")
+	assert.Equal(t, "2 configuration errors:", errs[0])
+	assert.Contains(t, errs[1], "missing.json: no such file or directory")
+	assert.Equal(t, `parameter "a" (from json): `+bad+`: json: cannot unmarshal number into Go value of type string`, errs[2])
+
+	// a custom Source may give a single path as-is, and a path from the
+	// environment isn't split on commas
+	comma := write("a,b.json", `{"a": "comma"}`)
+	vals, _, err = NewSourceJSON(SourceStub{"config-json-file": comma}).(sourceJSON).parseSet(detachedState(), pp)
+	require.NoError(t, err)
+	assert.Equal(t, "comma", vals["a"])
+
+	t.Setenv("CONFIG_JSON_FILE", comma)
+	vals, _, err = NewSourceJSON(NewSourceEnv()).(sourceJSON).parseSet(detachedState(), pp)
+	require.NoError(t, err)
+	assert.Equal(t, "comma", vals["a"])
+}
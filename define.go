@@ -0,0 +1,114 @@
+package lflag
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+)
+
+// parseFuncOf wraps a typed parse function as a ParseFunc
+func parseFuncOf[T any](parse func(string) (T, error)) ParseFunc {
+	return func(val string, ptr interface{}) error {
+		v, err := parse(val)
+		if err != nil {
+			return err
+		}
+		*(ptr.(*T)) = v
+		return nil
+	}
+}
+
+// definedParseFunc returns the ParseFunc for a param defined using DefineIn,
+// falling back to encoding.TextUnmarshaler if parse is nil
+func definedParseFunc[T any](parse func(string) (T, error)) ParseFunc {
+	if parse != nil {
+		return parseFuncOf(parse)
+	}
+	if _, ok := interface{}(new(T)).(encoding.TextUnmarshaler); !ok {
+		panic(fmt.Sprintf("lflag: no parse function given and *%s is not an encoding.TextUnmarshaler", paramTypeOf[T]()))
+	}
+	return parseTextUnmarshaler
+}
+
+// defaultString returns the Default used for a param defined with the given
+// value. The zero value is given as an empty string, otherwise MarshalText is
+// used if the value implements encoding.TextMarshaler, or fmt.Sprint if not.
+func defaultString(value interface{}) string {
+	if v := reflect.ValueOf(value); !v.IsValid() || v.IsZero() {
+		return ""
+	}
+	if tm, ok := value.(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		if err != nil {
+			panic(err)
+		}
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+// withParseFunc is a ParamOption which sets the ParseFunc used for a single
+// param, rather than the one for its ParamType
+func withParseFunc(fn ParseFunc) ParamOption {
+	return func(p *param) {
+		p.parse = fn
+	}
+}
+
+// paramTypeOf returns the ParamType used for params of type T defined using
+// Define, which is the name of the type, e.g. "time.Time"
+func paramTypeOf[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
+
+// DefineIn takes in a Set, the name of a config param, a default value, a
+// string describing the usage for the param, and a function which converts the
+// string value given by a Source into a T. It returns a pointer which will be
+// filled when Parse is called.
+//
+// If parse is nil then *T must implement encoding.TextUnmarshaler, and its
+// UnmarshalText method is used instead.
+//
+// The pointer is initialized to the default value, and parse is only called if
+// a Source gives the param a value. For the help string the default value is
+// converted to a string using MarshalText if T implements
+// encoding.TextMarshaler, or fmt.Sprint otherwise. In a json config file the value may be
+// a json string, or any other json value which will be passed to parse as-is.
+func DefineIn[T any](s *Set, name string, value T, usage string, parse func(string) (T, error), opts ...ParamOption) *T {
+	p := Param{
+		ParamType: paramTypeOf[T](),
+		Name:      name,
+		Default:   defaultString(value),
+		Usage:     usage,
+	}
+	ptr := new(T)
+	*ptr = value
+	opts = append([]ParamOption{withParseFunc(definedParseFunc(parse))}, opts...)
+	return s.newParam(p, ptr, opts...).(*T)
+}
+
+// Define calls DefineIn with CommandLine. For example:
+//
+//	since := lflag.Define("since", time.Time{}, "Only show entries after this time",
+//		func(s string) (time.Time, error) { return time.Parse(time.RFC3339, s) })
+func Define[T any](name string, value T, usage string, parse func(string) (T, error), opts ...ParamOption) *T {
+	return DefineIn(CommandLine, name, value, usage, parse, opts...)
+}
+
+// RequiredDefineIn is like DefineIn, but it has no default value and must be
+// set
+func RequiredDefineIn[T any](s *Set, name, usage string, parse func(string) (T, error), opts ...ParamOption) *T {
+	p := Param{
+		ParamType: paramTypeOf[T](),
+		Name:      name,
+		Usage:     usage,
+		Required:  true,
+	}
+	opts = append([]ParamOption{withParseFunc(definedParseFunc(parse))}, opts...)
+	return s.newParam(p, new(T), opts...).(*T)
+}
+
+// RequiredDefine calls RequiredDefineIn with CommandLine
+func RequiredDefine[T any](name, usage string, parse func(string) (T, error), opts ...ParamOption) *T {
+	return RequiredDefineIn(CommandLine, name, usage, parse, opts...)
+}
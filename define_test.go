@@ -0,0 +1,46 @@
+package lflag
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseIP(s string) (net.IP, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, &net.ParseError{Type: "IP address", Text: s}
+	}
+	return ip, nil
+}
+
+func TestDefine(t *testing.T) {
+	s := NewSet()
+	ip := DefineIn(s, "ip", net.IPv4(127, 0, 0, 1), "Some ip", parseIP)
+	ipDef := DefineIn(s, "ip-default", net.IPv4(127, 0, 0, 1), "Some ip", parseIP)
+	ts := RequiredDefineIn(s, "ts", "Some time", func(s string) (time.Time, error) {
+		return time.Parse(time.RFC3339, s)
+	})
+
+	err := s.ParseE(sourceJSON{
+		innerSrc:     SourceStub{"ip": "10.0.0.1"},
+		testJSONFile: bytes.NewBufferString(`{"ts": "2020-01-02T03:04:05Z"}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip.String())
+	assert.Equal(t, "127.0.0.1", ipDef.String())
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), *ts)
+
+	s = NewSet()
+	ip = DefineIn(s, "ip", net.IP(nil), "Some ip", parseIP)
+	assert.NoError(t, s.ParseE(SourceStub{}))
+	assert.Nil(t, *ip)
+
+	s = NewSet()
+	DefineIn(s, "ip", net.IP(nil), "Some ip", parseIP)
+	err = s.ParseE(SourceStub{"ip": "nope"})
+	assert.EqualError(t, err, `parameter "ip" (from stub): invalid IP address: nope`)
+}
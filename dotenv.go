@@ -0,0 +1,212 @@
+package lflag
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+type sourceDotEnv struct {
+	paths []string
+}
+
+// NewSourceDotEnv initializes and returns a new Source which will pull from the
+// given .env files, with the values in later files overwriting those in earlier
+// ones. Param names are mapped to variable names the same as NewSourceEnv does,
+// e.g. "listen-addr" is LISTEN_ADDR. Files which don't exist are ignored.
+//
+// Each line of a file is of the form NAME=value, optionally prefixed by
+// "export". Blank lines and those beginning with '#' are ignored, as is
+// anything following " #" in an unquoted value. Values may be quoted:
+//
+//   - Single quoted values are used exactly as-is, and may span multiple lines.
+//   - Double quoted values may span multiple lines, and may contain the escapes
+//     \n, \t, \r, \", \\ and \$, as well as variables.
+//   - Unquoted values have surrounding whitespace trimmed, and may contain
+//     variables.
+//
+// Variables, given as $NAME or ${NAME}, are expanded to the value of a variable
+// set earlier in the files, or of the environment variable if there isn't one.
+func NewSourceDotEnv(paths ...string) Source {
+	return sourceDotEnv{paths: paths}
+}
+
+// Parse implements the Source method
+func (sd sourceDotEnv) Parse(pp []Param) (map[string]string, error) {
+	vals, _, err := sd.parseDotEnvFiles(pp)
+	return vals, err
+}
+
+func (sd sourceDotEnv) parseSet(_ *parseState, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	vals, origins, err := sd.parseDotEnvFiles(pp)
+	if err != nil {
+		return vals, origins, sourceErrors(sd, err)
+	}
+	return vals, origins, nil
+}
+
+// parseDotEnvFiles reads the files and returns the values found in them for
+// the given params, along with the origin of each
+func (sd sourceDotEnv) parseDotEnvFiles(pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	var ee []string
+	files := map[string]string{} // the file each variable was last set by
+	vars := map[string]string{}
+	for _, path := range sd.paths {
+		f, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		fileEE, err := parseDotEnv(f, vars)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, e := range fileEE {
+			files[e[:strings.IndexByte(e, '=')]] = path
+		}
+		ee = append(ee, fileEE...)
+	}
+
+	vals, origins, err := NewSourceEnv().(sourceEnv).parseEnv(ee, pp)
+	for name, o := range origins {
+		o.Source, o.File = sd.String(), files[o.Key]
+		origins[name] = o
+	}
+	return vals, origins, err
+}
+
+func (sd sourceDotEnv) String() string {
+	return "dotenv"
+}
+
+// parseDotEnv parses a .env file into a list of NAME=value strings, like those
+// from os.Environ. vars holds the variables set by previous files, and is
+// updated with those set by this one.
+func parseDotEnv(r io.Reader, vars map[string]string) ([]string, error) {
+	lookup := func(name string) string {
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return os.Getenv(name)
+	}
+
+	var ee []string
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" || l[0] == '#' {
+			continue
+		} else if strings.HasPrefix(l, "export ") || strings.HasPrefix(l, "export\t") {
+			l = strings.TrimSpace(l[len("export"):])
+		}
+
+		i := strings.IndexByte(l, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected NAME=value, got %q", line, l)
+		}
+		name, raw := strings.TrimSpace(l[:i]), strings.TrimSpace(l[i+1:])
+
+		var val string
+		if len(raw) > 0 && (raw[0] == '\'' || raw[0] == '"') {
+			q, startLine := raw[0], line
+			end := dotEnvClosingQuote(raw, q)
+			for end < 0 && sc.Scan() {
+				line++
+				raw += "\n" + sc.Text()
+				end = dotEnvClosingQuote(raw, q)
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", startLine)
+			} else if rest := strings.TrimSpace(raw[end+1:]); rest != "" && rest[0] != '#' {
+				return nil, fmt.Errorf("line %d: unexpected %q after quoted value", line, rest)
+			}
+
+			val = raw[1:end]
+			if q == '"' {
+				val = dotEnvExpand(val, true, lookup)
+			}
+		} else {
+			if i := strings.Index(raw, " #"); i >= 0 {
+				raw = strings.TrimSpace(raw[:i])
+			}
+			val = dotEnvExpand(raw, false, lookup)
+		}
+
+		vars[name] = val
+		ee = append(ee, name+"="+val)
+	}
+	return ee, sc.Err()
+}
+
+// dotEnvClosingQuote returns the index of the quote closing the quoted value
+// at the start of s, or -1 if it isn't closed. Double quotes may be escaped.
+func dotEnvClosingQuote(s string, q byte) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' && q == '"' {
+			i++
+		} else if s[i] == q {
+			return i
+		}
+	}
+	return -1
+}
+
+// dotEnvExpand expands the variables in s using lookup, and also handles
+// backslash escapes if escapes is true
+func dotEnvExpand(s string, escapes bool, lookup func(string) string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && escapes && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		} else if c != '$' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		var name string
+		if s[i+1] == '{' {
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				b.WriteString(s[i:])
+				break
+			}
+			name, i = s[i+2:i+end], i+end
+		} else {
+			j := i + 1
+			for j < len(s) && isEnvNameChar(s[j], j == i+1) {
+				j++
+			}
+			if j == i+1 {
+				b.WriteByte(c)
+				continue
+			}
+			name, i = s[i+1:j], j-1
+		}
+		b.WriteString(lookup(name))
+	}
+	return b.String()
+}
+
+func isEnvNameChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(!first && c >= '0' && c <= '9')
+}
@@ -0,0 +1,66 @@
+package lflag
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDotEnv(t *T) {
+	t.Setenv("LFLAG_TEST_HOME", "/home/test")
+
+	ee, err := parseDotEnv(strings.NewReader(`
+# a comment
+FOO=bar
+export BAZ = baz # trailing comment
+EMPTY=
+SINGLE='no $FOO \n expansion'
+DOUBLE="line\none \"quoted\" \$FOO"
+MULTI="first
+second"
+EXPANDED=${FOO}-$BAZ-$LFLAG_TEST_HOME-$UNSET-$
+HASH=a#b
+`), map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"FOO=bar",
+		"BAZ=baz",
+		"EMPTY=",
+		`SINGLE=no $FOO \n expansion`,
+		"DOUBLE=line\none \"quoted\" $FOO",
+		"MULTI=first\nsecond",
+		"EXPANDED=bar-baz-/home/test--$",
+		"HASH=a#b",
+	}, ee)
+
+	_, err = parseDotEnv(strings.NewReader("FOO=bar\nnot a var\n"), map[string]string{})
+	assert.EqualError(t, err, `line 2: expected NAME=value, got "not a var"`)
+
+	_, err = parseDotEnv(strings.NewReader("FOO='bar\n\n"), map[string]string{})
+	assert.EqualError(t, err, "line 1: unterminated quoted value")
+}
+
+func TestSourceDotEnv(t *T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, ".env")
+	second := filepath.Join(dir, ".env.local")
+	require.NoError(t, os.WriteFile(first, []byte("FOO=foo\nBAR=bar\nSTRS=a, b\n"), 0600))
+	require.NoError(t, os.WriteFile(second, []byte("BAR=${FOO}2\n"), 0600))
+
+	pp := []Param{
+		{ParamType: ParamTypeString, Name: "foo"},
+		{ParamType: ParamTypeString, Name: "bar"},
+		{ParamType: ParamTypeStringSlice, Name: "strs"},
+	}
+	out, err := NewSourceDotEnv(first, filepath.Join(dir, "missing"), second).Parse(pp)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"foo":  "foo",
+		"bar":  "foo2",
+		"strs": `["a","b"]`,
+	}, out)
+}
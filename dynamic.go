@@ -0,0 +1,314 @@
+package lflag
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Dynamic holds the value of a param defined using DefineDynamicIn, which
+// unlike other params may change after Parse, see Set.Reload. It's safe to call
+// Get concurrently with a reload.
+type Dynamic[T any] struct {
+	v atomic.Value
+}
+
+// dynamicBox is what's stored in a Dynamic's atomic.Value, so that the stored
+// type is always the same even if T is an interface
+type dynamicBox[T any] struct {
+	v T
+}
+
+// Get returns the current value of the param
+func (d *Dynamic[T]) Get() T {
+	return d.v.Load().(dynamicBox[T]).v
+}
+
+func (d *Dynamic[T]) load() interface{} {
+	return d.Get()
+}
+
+func (d *Dynamic[T]) store(ptr interface{}) {
+	d.v.Store(dynamicBox[T]{*(ptr.(*T))})
+}
+
+func (d *Dynamic[T]) newPtr() interface{} {
+	return new(T)
+}
+
+// dynamicValue is implemented by *Dynamic
+type dynamicValue interface {
+	load() interface{}
+	store(ptr interface{})
+	newPtr() interface{}
+}
+
+type dynamicParam struct {
+	param
+	value    dynamicValue
+	onChange []func(old, new interface{})
+
+	// def points to the default value, which is used by Reload when the param
+	// has its own ParseFunc and isn't set by any Source, see DefineIn
+	def interface{}
+}
+
+// DefineDynamicIn is like DefineIn, but returns a Dynamic whose value is set by
+// Parse and then updated by each call to Reload, e.g. for settings like log
+// levels or rate limits which should be changeable without a restart. See also
+// OnChange and Watch.
+//
+// If parse is nil and T is one of the types supported by Struct, e.g. string,
+// int, or time.Duration, then the value is parsed the same as it would be for
+// the corresponding function, e.g. String. Otherwise it's parsed the same as
+// for DefineIn.
+func DefineDynamicIn[T any](s *Set, name string, value T, usage string, parse func(string) (T, error), opts ...ParamOption) *Dynamic[T] {
+	p := Param{Name: name, Usage: usage}
+	ptr := new(T)
+	*ptr = value
+	if paramType, ok := structFieldParamTypes[reflect.TypeOf(ptr).Elem()]; ok && parse == nil {
+		p.ParamType = paramType
+		p.Default = fieldDefault(reflect.ValueOf(ptr).Elem())
+	} else {
+		p.ParamType = paramTypeOf[T]()
+		p.Default = defaultString(value)
+		opts = append([]ParamOption{withParseFunc(definedParseFunc(parse))}, opts...)
+	}
+	s.newParam(p, ptr, opts...)
+
+	s.l.Lock()
+	defer s.l.Unlock()
+	if dp, ok := s.dynamic[name]; ok {
+		return dp.value.(*Dynamic[T])
+	}
+	def := new(T)
+	*def = value
+	d := new(Dynamic[T])
+	d.store(def)
+	if s.dynamic == nil {
+		s.dynamic = map[string]*dynamicParam{}
+	}
+	s.dynamic[name] = &dynamicParam{param: s.m[name], value: d, def: def}
+	return d
+}
+
+// DefineDynamic calls DefineDynamicIn with CommandLine
+func DefineDynamic[T any](name string, value T, usage string, parse func(string) (T, error), opts ...ParamOption) *Dynamic[T] {
+	return DefineDynamicIn(CommandLine, name, value, usage, parse, opts...)
+}
+
+// OnChange registers a function to be called by Reload whenever the value of
+// the named param, which must have been defined using DefineDynamicIn, changes.
+// It's called with the old and new values once the new one has been parsed and
+// validated, and after it's been stored in the Dynamic.
+func (s *Set) OnChange(name string, fn func(old, new interface{})) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	dp, ok := s.dynamic[name]
+	if !ok {
+		panic(fmt.Sprintf("param %q is not a dynamic param", name))
+	}
+	dp.onChange = append(dp.onChange, fn)
+}
+
+// OnChange calls OnChange on CommandLine
+func OnChange(name string, fn func(old, new interface{})) {
+	CommandLine.OnChange(name, fn)
+}
+
+// dynamicChange is a change to a dynamic param's value made by Reload
+type dynamicChange struct {
+	dp       *dynamicParam
+	old, new interface{}
+}
+
+// Reload reads the values of the dynamic params (see DefineDynamicIn) of the Set,
+// and of any selected subcommands, from the Source given to the last Parse. Each
+// one which was given a new valid value is updated, and the functions
+// registered for it using OnChange are called. It returns an Errors containing
+// every problem encountered, like ParseE, and params with a problem keep their
+// current value. If there's a problem which isn't specific to any param, e.g. a
+// config file which can't be decoded, then no values are changed.
+func (s *Set) Reload() error {
+	changes, err := s.reload()
+	for _, c := range changes {
+		for _, fn := range c.dp.onChange {
+			fn(c.old, c.new)
+		}
+	}
+	return err
+}
+
+// Reload calls Reload on CommandLine
+func Reload() error {
+	return CommandLine.Reload()
+}
+
+func (s *Set) reload() ([]dynamicChange, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.src == nil {
+		return nil, errors.New("Reload called before Parse")
+	}
+
+	var errs Errors
+	st := &parseState{set: s}
+	vals, origins, err := st.parseSource(s.src, s.parsed)
+	if err != nil {
+		errs = errs.append(err)
+	}
+	s.files = st.files
+
+	// an error which isn't specific to a param, e.g. a config file which
+	// couldn't be decoded, means values may be missing from vals, and so
+	// nothing is changed. Params with their own errors keep their value.
+	failed := map[string]bool{}
+	for _, e := range errs {
+		if e.Param == "" {
+			return nil, errs
+		}
+		failed[e.Param] = true
+	}
+
+	names := make([]string, 0, len(s.parsedDynamic))
+	for name := range s.parsedDynamic {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []dynamicChange
+	for _, name := range names {
+		dp := s.parsedDynamic[name]
+		if failed[name] {
+			continue
+		}
+		val, ok := vals[name]
+		origin := origins[name]
+		if !ok {
+			if dp.Required {
+				errs = append(errs, &Error{Param: name, Err: ErrRequired})
+				continue
+			}
+			val, origin = dp.Default, ParamOrigin{Source: "default"}
+		}
+		val = dp.choice(val)
+
+		if err := checkChoices(dp.Param, val); err != nil {
+			errs = append(errs, &Error{Param: name, Source: origin.Source, Err: redactError(dp.Param, err)})
+			continue
+		}
+
+		ptr := dp.value.newPtr()
+		if !ok && dp.parse != nil {
+			ptr = dp.def
+		} else if err := dp.parseFunc()(val, ptr); err != nil {
+			errs = append(errs, &Error{Param: name, Source: origin.Source, Err: redactError(dp.Param, err)})
+			continue
+		}
+
+		var invalid bool
+		for _, v := range dp.validators {
+			if err := v(ptr); err != nil {
+				errs = append(errs, &Error{Param: name, Source: origin.Source, Err: redactError(dp.Param, err)})
+				invalid = true
+			}
+		}
+		if invalid {
+			continue
+		}
+		s.values[name], s.origins[name] = val, origin
+
+		old := dp.value.load()
+		if v := reflect.ValueOf(ptr).Elem().Interface(); !reflect.DeepEqual(old, v) {
+			dp.value.store(ptr)
+			changes = append(changes, dynamicChange{dp: dp, old: old, new: v})
+		}
+	}
+
+	if len(errs) > 0 {
+		return changes, errs
+	}
+	return changes, nil
+}
+
+// Watch calls Reload whenever any of the config files read by the last Parse
+// (e.g. by NewSourceJSON) change, which is checked for every interval, or when
+// the process receives SIGHUP. If interval isn't positive then only SIGHUP is
+// watched for. Any error returned from Reload is passed to onErr, if it's not
+// nil. The returned function stops the watching.
+func (s *Set) Watch(interval time.Duration, onErr func(error)) (stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+
+	stamps := s.fileStamps()
+	stopCh := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer signal.Stop(sigCh)
+
+		var tickCh <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tickCh = ticker.C
+		}
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-sigCh:
+			case <-tickCh:
+				if newStamps := s.fileStamps(); reflect.DeepEqual(stamps, newStamps) {
+					continue
+				}
+			}
+			if err := s.Reload(); err != nil && onErr != nil {
+				onErr(err)
+			}
+			stamps = s.fileStamps()
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stopCh)
+			wg.Wait()
+		})
+	}
+}
+
+// Watch calls Watch on CommandLine
+func Watch(interval time.Duration, onErr func(error)) (stop func()) {
+	return CommandLine.Watch(interval, onErr)
+}
+
+// fileStamps returns the modification time and size of each of the config
+// files read by the last Parse or Reload, or an empty string for those which
+// can't be read
+func (s *Set) fileStamps() map[string]string {
+	s.l.Lock()
+	files := s.files
+	s.l.Unlock()
+
+	stamps := make(map[string]string, len(files))
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			stamps[f] = fmt.Sprintf("%s %d", info.ModTime(), info.Size())
+		} else {
+			stamps[f] = ""
+		}
+	}
+	return stamps
+}
@@ -0,0 +1,93 @@
+package lflag
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	. "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamic(t *T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(contents string) {
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	}
+	write(`{"rate": 5, "log-level": "info"}`)
+
+	s := NewSet()
+	rate := DefineDynamicIn(s, "rate", 10, "Requests per second", nil, Min(1))
+	level := DefineDynamicIn(s, "log-level", "warn", "Log level", nil)
+	ip := DefineDynamicIn(s, "ip", net.IPv4(127, 0, 0, 1), "Some ip", nil)
+	static := s.String("static", "", "Not dynamic")
+	assert.Equal(t, 10, rate.Get())
+	assert.Panics(t, func() {
+		s.OnChange("static", func(old, new interface{}) {})
+	})
+
+	var changes [][2]interface{}
+	s.OnChange("rate", func(old, new interface{}) {
+		changes = append(changes, [2]interface{}{old, new})
+	})
+
+	assert.EqualError(t, s.Reload(), "Reload called before Parse")
+
+	src := NewSourceJSON(SourceStub{"config-json-file": path})
+	require.NoError(t, s.ParseE(src))
+	assert.Equal(t, 5, rate.Get())
+	assert.Equal(t, "info", level.Get())
+	assert.Equal(t, "127.0.0.1", ip.Get().String())
+
+	write(`{"rate": 7, "ip": "10.0.0.1", "static": "changed"}`)
+	require.NoError(t, s.Reload())
+	assert.Equal(t, 7, rate.Get())
+	assert.Equal(t, "warn", level.Get())
+	assert.Equal(t, "10.0.0.1", ip.Get().String())
+	assert.Equal(t, "", *static)
+	assert.Equal(t, [][2]interface{}{{5, 7}}, changes)
+
+	// invalid values are reported and the current value is kept
+	write(`{"rate": 0}`)
+	assert.EqualError(t, s.Reload(), `parameter "rate" (from json): must be at least 1`)
+	assert.Equal(t, 7, rate.Get())
+	assert.Equal(t, "127.0.0.1", ip.Get().String())
+	assert.Len(t, changes, 1)
+
+	// as are errors in the file itself, which leave every value as it is
+	write(`{"rate": 3, "log-level": "debug"}`)
+	require.NoError(t, s.Reload())
+	write(`{"rate": 5,`)
+	err := s.Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+": unexpected EOF")
+	assert.Equal(t, 3, rate.Get())
+	assert.Equal(t, "debug", level.Get())
+	assert.Equal(t, [][2]interface{}{{5, 7}, {7, 3}}, changes)
+}
+
+func TestDynamicWatch(t *T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rate": 5}`), 0600))
+
+	s := NewSet()
+	rate := DefineDynamicIn(s, "rate", 10, "Requests per second", nil)
+	changed := make(chan interface{}, 1)
+	s.OnChange("rate", func(old, new interface{}) { changed <- new })
+	require.NoError(t, s.ParseE(NewSourceJSON(SourceStub{"config-json-file": path})))
+
+	stop := s.Watch(10*time.Millisecond, func(err error) { t.Error(err) })
+	defer stop()
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"rate": 500}`), 0600))
+	select {
+	case v := <-changed:
+		assert.Equal(t, 500, v)
+		assert.Equal(t, 500, rate.Get())
+	case <-time.After(5 * time.Second):
+		t.Fatal("change wasn't noticed")
+	}
+	stop()
+}
@@ -0,0 +1,244 @@
+package lflag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// originsKey is the key under which the origins of the params are given in the
+// json output of WriteEffective. NewSourceJSON ignores it, since it doesn't
+// correspond to any param.
+const originsKey = "$origins"
+
+// effectiveFormats are the formats supported by WriteEffective
+var effectiveFormats = []string{"json", "yaml", "table"}
+
+func checkEffectiveFormat(format string) error {
+	for _, f := range effectiveFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q must be one of: %s", format, strings.Join(effectiveFormats, ", "))
+}
+
+// EffectiveParam describes the value given to a param by the last Parse, see
+// Effective
+type EffectiveParam struct {
+	Param
+
+	// Value is the string form of the param's value, as given to its
+	// ParseFunc. If the param is Secret then it's "<redacted>", as is Default.
+	Value string
+
+	// Origin is where the value came from, see Origin
+	Origin ParamOrigin
+}
+
+// Effective returns every param of the Set, and of any subcommands selected by
+// the last Parse, along with the value it was given and where that came from,
+// sorted by name. The values of Secret params are redacted. The values of
+// dynamic params (see DefineDynamicIn) are updated by Reload.
+func (s *Set) Effective() []EffectiveParam {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	ee := make([]EffectiveParam, len(s.parsed))
+	for i, p := range s.parsed {
+		ee[i] = EffectiveParam{Param: p, Value: s.values[p.Name], Origin: s.origins[p.Name]}
+		if p.Secret {
+			ee[i].Value = redactedValue
+			if p.Default != "" {
+				ee[i].Default = redactedValue
+			}
+		}
+	}
+	return ee
+}
+
+// Effective calls Effective on CommandLine
+func Effective() []EffectiveParam {
+	return CommandLine.Effective()
+}
+
+// WriteEffective writes the params returned from Effective to w, in the given
+// format:
+//
+//   - "json" writes a json object which may be read back in using
+//     NewSourceJSON, with the origin of each param given under "$origins".
+//   - "yaml" writes a yaml mapping which may be read back in using
+//     NewSourceYAML, with the origin of each param given in a comment.
+//   - "table" writes a table of each param's name, value and origin.
+//
+// Secret params are left out of the json and yaml values, so that reading them
+// back in doesn't overwrite a secret with its redacted form.
+func (s *Set) WriteEffective(w io.Writer, format string) error {
+	if err := checkEffectiveFormat(format); err != nil {
+		return err
+	}
+
+	ee := s.Effective()
+	switch format {
+	case "json":
+		return writeEffectiveJSON(w, ee)
+	case "yaml":
+		return writeEffectiveYAML(w, ee)
+	}
+	return writeEffectiveTable(w, ee)
+}
+
+// WriteEffective calls WriteEffective on CommandLine
+func WriteEffective(w io.Writer, format string) error {
+	return CommandLine.WriteEffective(w, format)
+}
+
+// effectiveJSON returns the json value which NewSourceJSON would turn back into
+// the param's value, see paramTypeJSONStringers, or false if the param should be
+// left out
+func effectiveJSON(ep EffectiveParam) (interface{}, bool) {
+	if ep.Secret {
+		return nil, false
+	}
+
+	switch ep.ParamType {
+	case ParamTypeString, ParamTypeDuration:
+		return ep.Value, true
+	case ParamTypeBool:
+		// the same rule as parseParamTypeBool, so e.g. "yes" from env stays true
+		return ep.Value != "" && ep.Value != "false", true
+	case ParamTypeStringSlice, ParamTypeIntSlice, ParamTypeDurationSlice:
+		strs, err := splitList(ep.Value)
+		if err != nil {
+			return nil, false
+		}
+		vv := make([]interface{}, len(strs))
+		for i := range strs {
+			if ep.ParamType == ParamTypeIntSlice {
+				vv[i] = effectiveJSONAuto(strs[i])
+			} else {
+				vv[i] = strs[i]
+			}
+		}
+		return vv, true
+	case ParamTypeStringMap:
+		m := map[string]string{}
+		if ep.Value != "" {
+			if err := json.Unmarshal([]byte(ep.Value), &m); err != nil {
+				return nil, false
+			}
+		}
+		return m, true
+	}
+
+	// an empty value isn't valid for e.g. an Int, and leaving it out has the
+	// same effect
+	if ep.Value == "" {
+		return nil, false
+	}
+	return effectiveJSONAuto(ep.Value), true
+}
+
+// effectiveJSONAuto is the inverse of JSONStringAuto
+func effectiveJSONAuto(val string) interface{} {
+	if json.Valid([]byte(val)) && val[0] != '"' {
+		return json.RawMessage(val)
+	}
+	return val
+}
+
+func writeEffectiveJSON(w io.Writer, ee []EffectiveParam) error {
+	out := make(map[string]interface{}, len(ee)+1)
+	origins := make(map[string]string, len(ee))
+	for _, ep := range ee {
+		if v, ok := effectiveJSON(ep); ok {
+			out[ep.Name] = v
+		}
+		origins[ep.Name] = ep.Origin.String()
+	}
+	out[originsKey] = origins
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func writeEffectiveYAML(w io.Writer, ee []EffectiveParam) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	var secrets []string
+	for _, ep := range ee {
+		v, ok := effectiveJSON(ep)
+		if !ok {
+			if ep.Secret {
+				secrets = append(secrets, fmt.Sprintf("%s: %s # %s", ep.Name, redactedValue, ep.Origin))
+			}
+			continue
+		}
+
+		// json is valid yaml, so the value is converted by way of it
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var n yaml.Node
+		if err := yaml.Unmarshal(b, &n); err != nil {
+			return err
+		}
+		val := n.Content[0]
+		yamlBlockStyle(val)
+
+		// the comment would end up after a list or mapping, rather than on the
+		// same line as the key, if it were put on the value
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: ep.Name}
+		if val.Kind == yaml.ScalarNode {
+			val.LineComment = ep.Origin.String()
+		} else {
+			key.LineComment = ep.Origin.String()
+		}
+		doc.Content = append(doc.Content, key, val)
+	}
+	// secrets are only shown in comments, see WriteEffective
+	doc.FootComment = strings.Join(secrets, "\n")
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// yamlBlockStyle clears the style of a yaml node decoded from json, and of the
+// nodes within it, so that it's encoded in the usual yaml style rather than as
+// json
+func yamlBlockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		yamlBlockStyle(c)
+	}
+}
+
+func writeEffectiveTable(w io.Writer, ee []EffectiveParam) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprint(tw, "NAME\tVALUE\tORIGIN\n")
+	for _, ep := range ee {
+		// values are only quoted when needed to keep the table readable
+		val := ep.Value
+		if ep.Secret {
+			val = redactedValue
+		} else if val == "" || strings.IndexFunc(val, func(r rune) bool {
+			return unicode.IsSpace(r) || !unicode.IsPrint(r)
+		}) >= 0 {
+			val = strconv.Quote(val)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", ep.Name, val, ep.Origin)
+	}
+	return tw.Flush()
+}
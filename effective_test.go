@@ -0,0 +1,164 @@
+package lflag
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	. "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffective(t *T) {
+	type conf struct {
+		addr, password, mode *string
+		port                 *int
+		ratio                *float64
+		verbose              *bool
+		timeout              *time.Duration
+		tags                 *[]string
+		ids                  *[]int
+		labels               *map[string]string
+		extra                *map[string]interface{}
+	}
+	newSet := func() (*Set, conf) {
+		s := NewSet()
+		var c conf
+		c.addr = s.String("db-addr", "localhost:5432", "Database address")
+		c.password = s.String("db-password", "changeme", "Database password", Secret())
+		c.mode = s.String("mode", "", "Mode")
+		c.port = s.Int("port", 0, "Port")
+		c.ratio = s.Float64("ratio", 0.5, "Ratio")
+		c.verbose = s.Bool("verbose", false, "Verbose")
+		c.timeout = s.Duration("timeout", time.Second, "Timeout")
+		c.tags = s.StringSlice("tags", nil, "Tags")
+		c.ids = s.IntSlice("ids", nil, "IDs")
+		c.labels = s.StringMap("labels", nil, "Labels")
+		c.extra = new(map[string]interface{})
+		s.JSON(c.extra, "extra", nil, "Extra")
+		return s, c
+	}
+
+	s, c := newSet()
+	require.NoError(t, s.ParseE(sourceCLI{testArgs: []string{
+		"--db-password", "hunter2", "--mode", "true", "--port", "8080",
+		"--verbose", "--timeout", "1m", "--tags", "a", "--tags", "b: c",
+		"--ids", "1", "--ids", "2", "--labels", "env=prod",
+		"--extra", `{"n": [1, "x"]}`,
+	}}))
+
+	ee := s.Effective()
+	require.Len(t, ee, 11)
+	assert.Equal(t, "db-password", ee[1].Name)
+	assert.Equal(t, redactedValue, ee[1].Value)
+	assert.Equal(t, redactedValue, ee[1].Default)
+	assert.Equal(t, ParamOrigin{Source: "cli", Key: "--db-password", Arg: 1}, ee[1].Origin)
+
+	// both json and yaml should read back in to the same values, other than the
+	// secret
+	assertRoundTrip := func(src Source) {
+		s2, c2 := newSet()
+		require.NoError(t, s2.ParseE(src))
+		c.password = c2.password
+		assert.Equal(t, c, c2)
+		assert.Equal(t, "changeme", *c2.password)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, s.WriteEffective(&buf, "json"))
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.Contains(t, buf.String(), `"db-password": "cli --db-password, arg 1"`)
+	assert.Contains(t, buf.String(), `"db-addr": "default"`)
+	assertRoundTrip(sourceJSON{innerSrc: SourceStub{}, testJSONFile: &buf})
+
+	buf.Reset()
+	require.NoError(t, s.WriteEffective(&buf, "yaml"))
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.Contains(t, buf.String(), "db-addr: localhost:5432 # default\n")
+	assert.Contains(t, buf.String(), "# db-password: <redacted> # cli --db-password, arg 1\n")
+	assertRoundTrip(sourceYAML{innerSrc: SourceStub{}, testYAMLFile: &buf})
+
+	buf.Reset()
+	require.NoError(t, s.WriteEffective(&buf, "table"))
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.Contains(t, buf.String(), "db-password  <redacted>")
+
+	assert.EqualError(t, s.WriteEffective(&buf, "xml"), `"xml" must be one of: json, yaml, table`)
+
+	// a bool which is true other than by being "true" is still dumped as true
+	s, c = newSet()
+	require.NoError(t, s.ParseE(SourceStub{"verbose": "yes"}))
+	require.True(t, *c.verbose)
+	buf.Reset()
+	require.NoError(t, s.WriteEffective(&buf, "json"))
+	assert.Contains(t, buf.String(), `"verbose": true`)
+	s2, c2 := newSet()
+	require.NoError(t, s2.ParseE(sourceJSON{innerSrc: SourceStub{}, testJSONFile: &buf}))
+	assert.True(t, *c2.verbose)
+}
+
+func TestSecret(t *T) {
+	errBadToken := errors.New("bad token")
+	s := NewSet()
+	s.String("token", "s3cret-default", "API token", Secret(), Check(func(token string) error {
+		return fmt.Errorf("%w: %q", errBadToken, token)
+	}))
+	s.Int("pin", 0, "PIN", Secret())
+
+	err := s.ParseE(SourceStub{"token": "hunter2", "pin": `hun"ter2`})
+	assert.EqualError(t, err, `2 configuration errors:
+	parameter "pin" (from stub): invalid value <redacted>
+	parameter "token" (from stub): invalid value <redacted>`)
+	assert.True(t, errors.Is(err, errBadToken))
+
+	// errors from Sources are redacted too
+	s = NewSet()
+	s.StringMap("headers", nil, "Headers", Secret())
+	err = s.ParseE(sourceCLI{testArgs: []string{"--headers", "Authorization:Bearer-xyz"}})
+	assert.EqualError(t, err, `parameter "headers" (from cli): invalid value <redacted>`)
+
+	t.Setenv("HEADERS", "Authorization:Bearer-xyz")
+	err = s.ParseE(NewSourceEnv())
+	assert.EqualError(t, err, `parameter "headers" (from env): invalid value <redacted>`)
+
+	err = s.ParseE(sourceJSON{innerSrc: SourceStub{}, testJSONFile: strings.NewReader(
+		`{"headers": "Bearer-xyz"}`,
+	)})
+	assert.EqualError(t, err, `parameter "headers" (from json): invalid value <redacted>`)
+
+	s = NewSet()
+	s.String("token", "s3cret-default", "API token", Secret())
+
+	help := cliHelpStr("", s, nil, []Param{s.m["token"].Param})
+	assert.NotContains(t, help, "s3cret-default")
+	assert.Contains(t, help, "Default: "+redactedValue)
+}
+
+func TestPrintConfig(t *T) {
+	s := NewSet()
+	s.String("name", "", "Name")
+
+	st := &parseState{set: s}
+	_, _, _, err := sourceCLI{}.parseCLI(st, []string{"--print-config=yaml", "--name", "foo"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "yaml", st.printConfig)
+
+	_, _, _, err = sourceCLI{}.parseCLI(st, []string{"--print-config"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "json", st.printConfig)
+	assert.Empty(t, s.printConfig)
+
+	// calling Parse directly doesn't touch CommandLine
+	_, err = sourceCLI{testArgs: []string{"--print-config"}}.Parse(nil)
+	require.NoError(t, err)
+	assert.Empty(t, CommandLine.printConfig)
+
+	_, _, _, err = sourceCLI{}.parseCLI(detachedState(), []string{"--print-config=xml"}, nil)
+	assert.EqualError(t, err, `--print-config: "xml" must be one of: json, yaml, table`)
+
+	help := cliHelpStr("", s, nil, nil)
+	assert.True(t, strings.Contains(help, "\t--print-config (flag)\n"))
+}
@@ -6,38 +6,231 @@ import (
 	"strings"
 )
 
-type sourceEnv struct{}
+type sourceEnv struct {
+	separator    string
+	strictPrefix string
+	prefix       string
+	nameFn       func(string) string
+	files        bool
+}
+
+// EnvOption is used to modify the behavior of the Source returned from
+// NewSourceEnv
+type EnvOption func(*sourceEnv)
+
+// EnvSeparator sets the separator which the values of list and map params (e.g.
+// StringSlice or StringMap) are split on. The default is ",".
+func EnvSeparator(sep string) EnvOption {
+	return func(se *sourceEnv) {
+		se.separator = sep
+	}
+}
+
+// EnvPrefix causes every param to be read from an environment variable
+// beginning with the given prefix, e.g. with EnvPrefix("MYSVC_") the
+// "listen-addr" param is read from "MYSVC_LISTEN_ADDR". This avoids collisions
+// between binaries sharing an environment, and with common variables like HOME.
+func EnvPrefix(prefix string) EnvOption {
+	return func(se *sourceEnv) {
+		se.prefix = prefix
+	}
+}
+
+// EnvNameFunc sets the function used to map a param's name to the name of the
+// environment variable it's read from, in place of the default uppercasing. Any
+// prefix given by EnvPrefix is still prepended to the returned name.
+func EnvNameFunc(fn func(name string) string) EnvOption {
+	return func(se *sourceEnv) {
+		se.nameFn = fn
+	}
+}
+
+// EnvFiles causes a param's value to also be read from the file named by the
+// param's environment variable with "_FILE" appended, e.g. DB_PASSWORD_FILE for
+// "db-password", which is the usual convention for secrets mounted by Docker or
+// Kubernetes. Any trailing newline is removed from the file's contents.
+//
+// If both variables are set the direct one, e.g. DB_PASSWORD, takes precedence.
+// It's an error for the file to not exist or not be readable. Positional,
+// boolean, list and map params can't be read from a file.
+func EnvFiles() EnvOption {
+	return func(se *sourceEnv) {
+		se.files = true
+	}
+}
+
+// EnvStrict causes any environment variable which begins with the given prefix
+// but doesn't correspond to a param to be an error. The error will suggest the
+// closest variable name, if there's one which is similar. The prefix must not
+// be empty, since every environment variable would match it.
+func EnvStrict(prefix string) EnvOption {
+	if prefix == "" {
+		panic("lflag: EnvStrict requires a non-empty prefix")
+	}
+	return func(se *sourceEnv) {
+		se.strictPrefix = prefix
+	}
+}
 
 // NewSourceEnv initializes and returns a new Source which will pull from the
 // environment variables at runtime. All param names are completely uppercased
-// and have '-' replaced with '_', e.g "listen-addr" becomes "LISTEN_ADDR"
-func NewSourceEnv() Source {
-	return sourceEnv{}
+// and have '-' replaced with '_', e.g "listen-addr" becomes "LISTEN_ADDR",
+// unless changed using EnvPrefix or EnvNameFunc. When used alongside
+// NewSourceCLI the variable names are shown in the --help output.
+//
+// The values of list params are split on a separator (see EnvSeparator), and
+// surrounding whitespace is trimmed from each element. The values of map params
+// are split the same way, with each element being a "key=value" pair, e.g.
+// "env=prod,team=infra". The names of config files, e.g. CONFIG_JSON_FILE for
+// NewSourceJSON, aren't split, since a path may contain the separator.
+func NewSourceEnv(opts ...EnvOption) Source {
+	se := sourceEnv{separator: ","}
+	for _, opt := range opts {
+		opt(&se)
+	}
+	return se
 }
 
 // Parse implements the Source method
 func (se sourceEnv) Parse(pp []Param) (map[string]string, error) {
-	return parseEnv(os.Environ(), pp)
+	vals, _, err := se.parseEnv(os.Environ(), pp)
+	return vals, err
 }
 
-// split out for testing
-func parseEnv(ee []string, pp []Param) (map[string]string, error) {
+func (se sourceEnv) parseSet(_ *parseState, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	vals, origins, err := se.parseEnv(os.Environ(), pp)
+	if err != nil {
+		return vals, origins, sourceErrors(se, err)
+	}
+	return vals, origins, nil
+}
+
+func (se sourceEnv) String() string {
+	return "env"
+}
+
+// envName returns the name of the environment variable the named param is read
+// from
+func (se sourceEnv) envName(name string) string {
+	if se.nameFn != nil {
+		return se.prefix + se.nameFn(name)
+	}
+	return se.prefix + strings.Replace(strings.ToUpper(name), "-", "_", -1)
+}
+
+// split out for testing. Returns the found values along with their origins,
+// which are returned even if there's an error so that every problem can be
+// reported at once.
+func (se sourceEnv) parseEnv(ee []string, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
 	envM := map[string]Param{}
 	for _, p := range pp {
-		name := strings.ToUpper(p.Name)
-		name = strings.Replace(name, "-", "_", -1)
-		envM[name] = p
+		if p.Positional > 0 {
+			continue
+		}
+		envM[se.envName(p.Name)] = p
+	}
+
+	// fileM maps the "_FILE" variables to the params they're for
+	fileM := map[string]Param{}
+	if se.files {
+		for _, p := range pp {
+			name := se.envName(p.Name) + "_FILE"
+			if _, ok := envM[name]; fileParam(p) && !ok {
+				fileM[name] = p
+			}
+		}
 	}
+	// files holds the "_FILE" variable and its value found for each param
+	files := map[string][2]string{}
 
 	ret := map[string]string{}
+	origins := map[string]ParamOrigin{}
+	var errs Errors
 	for _, e := range ee {
 		envParts := strings.SplitN(e, "=", 2)
 		if len(envParts) != 2 {
-			return nil, fmt.Errorf("malformed environment variable: %q", e)
+			errs = append(errs, &Error{Err: fmt.Errorf("malformed environment variable: %q", e)})
+			continue
 		}
-		if p, ok := envM[envParts[0]]; ok {
+		if p, ok := fileM[envParts[0]]; ok && envParts[1] != "" {
+			files[p.Name] = [2]string{envParts[0], envParts[1]}
+			continue
+		}
+
+		p, ok := envM[envParts[0]]
+		if !ok {
+			if se.strictPrefix != "" && strings.HasPrefix(envParts[0], se.strictPrefix) {
+				names := make([]string, 0, len(envM))
+				for name := range envM {
+					names = append(names, name)
+				}
+				errs = append(errs, &Error{Err: unknownError(envParts[0], names)})
+			}
+			continue
+		}
+		if p.paths && envParts[1] != "" {
+			ret[p.Name] = joinList([]string{envParts[1]})
+		} else if _, ok := listParamTypes[p.ParamType]; ok {
+			ret[p.Name] = se.splitList(envParts[1])
+		} else if p.ParamType == ParamTypeStringMap {
+			m, err := se.splitMap(envParts[1])
+			if err != nil {
+				errs = append(errs, &Error{Param: p.Name, Err: redactError(p, err)})
+				continue
+			}
+			ret[p.Name] = m
+		} else {
 			ret[p.Name] = envParts[1]
 		}
+		origins[p.Name] = ParamOrigin{Source: se.String(), Key: envParts[0]}
+	}
+
+	for name, file := range files {
+		if _, ok := ret[name]; ok {
+			continue
+		}
+		val, err := readValueFile(file[1])
+		if err != nil {
+			errs = append(errs, &Error{Param: name, Err: fmt.Errorf("reading %s: %w", file[0], err)})
+			continue
+		}
+		ret[name] = val
+		origins[name] = ParamOrigin{Source: se.String(), Key: file[0], File: file[1]}
+	}
+
+	if len(errs) > 0 {
+		return ret, origins, errs
+	}
+	return ret, origins, nil
+}
+
+// splitList converts the value of an environment variable for a list param into
+// the list param's string form
+func (se sourceEnv) splitList(val string) string {
+	// an empty variable is treated as an empty list
+	if val == "" {
+		return joinListKeepEmpty(nil)
+	}
+	strs := strings.Split(val, se.separator)
+	for i := range strs {
+		strs[i] = strings.TrimSpace(strs[i])
+	}
+	return joinList(strs)
+}
+
+// splitMap converts the value of an environment variable for a map param into
+// the map param's string form
+func (se sourceEnv) splitMap(val string) (string, error) {
+	if val == "" {
+		return joinMapKeepEmpty(nil), nil
+	}
+	m := map[string]string{}
+	for _, kv := range strings.Split(val, se.separator) {
+		k, v, err := splitKeyValue(strings.TrimSpace(kv))
+		if err != nil {
+			return "", err
+		}
+		m[k] = v
 	}
-	return ret, nil
+	return joinMap(m), nil
 }
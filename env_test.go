@@ -1,6 +1,7 @@
 package lflag
 
 import (
+	"strings"
 	. "testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,7 +17,7 @@ func TestSourceEnv(t *T) {
 		"FOO_BAR=okthen",
 	}
 
-	out, err := parseEnv(env, testParams)
+	out, _, err := NewSourceEnv().(sourceEnv).parseEnv(env, testParams)
 	require.Nil(t, err)
 	assert.Equal(t, map[string]string{
 		"foo":     "",
@@ -24,5 +25,84 @@ func TestSourceEnv(t *T) {
 		"flag1":   "true",
 		"foo-bar": "okthen",
 	}, out)
+}
+
+func TestSourceEnvList(t *T) {
+	pp := []Param{
+		{ParamType: ParamTypeStringSlice, Name: "peers"},
+		{ParamType: ParamTypeIntSlice, Name: "ports"},
+		{ParamType: ParamTypeStringSlice, Name: "empty"},
+		{ParamType: ParamTypeStringMap, Name: "labels"},
+	}
+	env := []string{
+		"PEERS=a:1; b:2",
+		"PORTS=1;2",
+		"EMPTY=",
+		"LABELS=env=prod; team=infra",
+	}
+
+	out, _, err := NewSourceEnv(EnvSeparator(";")).(sourceEnv).parseEnv(env, pp)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"peers":  `["a:1","b:2"]`,
+		"ports":  `["1","2"]`,
+		"empty":  "[]",
+		"labels": `{"env":"prod","team":"infra"}`,
+	}, out)
+}
+
+func TestSourceEnvStrict(t *T) {
+	env := []string{
+		"FOO_BAZ=okthen",
+		"FOO_BARR=okthen",
+		"FOO_BAR=okthen",
+		"HOME=whatever",
+	}
+
+	out, _, err := NewSourceEnv(EnvStrict("FOO_")).(sourceEnv).parseEnv(env, testParams)
+	assert.EqualError(t, err, `2 configuration errors:
+	unknown parameter "FOO_BAZ", did you mean "FOO_BAR"?
+	unknown parameter "FOO_BARR", did you mean "FOO_BAR"?`)
+	assert.Equal(t, "okthen", out["foo-bar"])
+
+	// the valid values are still used, so that only the real problems are
+	// reported
+	t.Setenv("FOO_BAR", "ok")
+	t.Setenv("FOO_TYPO", "x")
+	s := NewSet()
+	s.RequiredString("foo-bar", "")
+	err = s.ParseE(NewSourceEnv(EnvStrict("FOO_")))
+	assert.EqualError(t, err, `env: unknown parameter "FOO_TYPO"`)
+
+	// as are those alongside a malformed variable
+	out, _, err = NewSourceEnv().(sourceEnv).parseEnv([]string{"FOO_BAR=ok", "NOEQUALS"}, testParams)
+	assert.EqualError(t, err, `malformed environment variable: "NOEQUALS"`)
+	assert.Equal(t, "ok", out["foo-bar"])
+}
+
+func TestSourceEnvPrefix(t *T) {
+	env := []string{
+		"FOO=unprefixed",
+		"MYSVC_FOO=foo",
+		"MYSVC_foo.bar=okthen",
+	}
+
+	se := NewSourceEnv(EnvPrefix("MYSVC_")).(sourceEnv)
+	out, _, err := se.parseEnv(env, testParams)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"foo": "foo"}, out)
+
+	se = NewSourceEnv(EnvPrefix("MYSVC_"), EnvNameFunc(func(name string) string {
+		return strings.Replace(name, "-", ".", -1)
+	})).(sourceEnv)
+	out, _, err = se.parseEnv(env, testParams)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"foo-bar": "okthen"}, out)
 
+	help := cliHelpStr("", NewSet(), Sources{NewSourceEnv(EnvPrefix("MYSVC_")), NewSourceCLI()}, []Param{
+		{ParamType: ParamTypeString, Name: "listen-addr", Usage: "Address to listen on"},
+		{ParamType: ParamTypeString, Name: "in", Positional: 1},
+	})
+	assert.Contains(t, help, "\t--listen-addr\n\t\tAddress to listen on\n\t\tEnvironment: MYSVC_LISTEN_ADDR\n")
+	assert.NotContains(t, help, "MYSVC_IN")
 }
@@ -0,0 +1,189 @@
+package lflag
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrRequired is wrapped by an Error returned from ParseE when a Required param
+// was not given a value by any Source
+var ErrRequired = errors.New("required but not set")
+
+// ErrUnknown is wrapped by an Error when a Source in strict mode (e.g. see
+// CLIStrict) is given a parameter which hasn't been defined
+var ErrUnknown = errors.New("unknown parameter")
+
+// ErrCommandRequired is wrapped by an Error returned from ParseE when a Set has
+// subcommands defined on it but none was selected, see Command
+var ErrCommandRequired = errors.New("a command is required")
+
+// unknownError returns an error wrapping ErrUnknown for the given name, which
+// suggests the closest of the given known names if there's one which is
+// similar enough
+func unknownError(name string, known []string) error {
+	if closest := closestName(name, known); closest != "" {
+		return fmt.Errorf("%w %q, did you mean %q?", ErrUnknown, name, closest)
+	}
+	return fmt.Errorf("%w %q", ErrUnknown, name)
+}
+
+// redactedValue is shown in place of the value of a Secret param
+const redactedValue = "<redacted>"
+
+// redactedError wraps an error encountered for the value of a Secret param.
+// Since the value may appear in the error's message in any form, e.g. quoted,
+// the message is replaced entirely.
+type redactedError struct {
+	err error
+}
+
+func (e redactedError) Error() string {
+	return "invalid value " + redactedValue
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError returns the error encountered for the value of the param, wrapped
+// in a redactedError if the param is Secret
+func redactError(p Param, err error) error {
+	if !p.Secret {
+		return err
+	}
+	return redactedError{err: err}
+}
+
+// closestName returns the name out of names which is closest to the given one
+// by edit distance, or empty string if none are close enough to be a likely
+// typo
+func closestName(name string, names []string) string {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+
+	var closest string
+	maxDist := len(strings.TrimLeft(name, "-"))/4 + 1
+	for _, n := range names {
+		if d := editDistance(name, n); d <= maxDist {
+			closest, maxDist = n, d-1
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between the two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j] + 1
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+			if prev[j-1]+cost < curr[j] {
+				curr[j] = prev[j-1] + cost
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// Error describes a single problem encountered by ParseE. Param is the name of
+// the param which the error pertains to, and will be empty if the error isn't
+// specific to any one param (e.g. if a Source's Parse returned an error).
+// Source is the name of the Source which supplied the offending value, if any.
+type Error struct {
+	Param  string
+	Source string
+	Err    error
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Param == "" && e.Source == "" {
+		return e.Err.Error()
+	} else if e.Param == "" {
+		return fmt.Sprintf("%s: %v", e.Source, e.Err)
+	} else if e.Source == "" {
+		return fmt.Sprintf("parameter %q: %v", e.Param, e.Err)
+	}
+	return fmt.Sprintf("parameter %q (from %s): %v", e.Param, e.Source, e.Err)
+}
+
+// Unwrap returns the underlying error, for use by errors.Is and errors.As
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors is the error type returned by ParseE. It contains every problem
+// encountered while parsing, so that they can all be fixed at once.
+type Errors []*Error
+
+// Error implements the error interface
+func (ee Errors) Error() string {
+	if len(ee) == 1 {
+		return ee[0].Error()
+	}
+	strs := make([]string, len(ee))
+	for i, e := range ee {
+		strs[i] = "\t" + e.Error()
+	}
+	return fmt.Sprintf("%d configuration errors:\n%s", len(ee), strings.Join(strs, "\n"))
+}
+
+// Is returns whether any of the contained errors matches target, for use by
+// errors.Is
+func (ee Errors) Is(target error) bool {
+	for _, e := range ee {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the contained errors which matches target, for use by
+// errors.As
+func (ee Errors) As(target interface{}) bool {
+	for _, e := range ee {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns each of the contained errors. It's only used by errors.Is and
+// errors.As on Go 1.20 and later, which see the same errors as Is and As.
+func (ee Errors) Unwrap() []error {
+	errs := make([]error, len(ee))
+	for i, e := range ee {
+		errs[i] = e
+	}
+	return errs
+}
+
+// append adds the given error to the Errors, flattening it if it's itself an
+// Errors or an *Error
+func (ee Errors) append(err error) Errors {
+	var eee Errors
+	var e *Error
+	if errors.As(err, &eee) {
+		return append(ee, eee...)
+	} else if errors.As(err, &e) {
+		return append(ee, e)
+	}
+	return append(ee, &Error{Err: err})
+}
@@ -0,0 +1,122 @@
+package lflag
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readValueFile returns the contents of the file at the given path, with any
+// trailing newline removed, for use as a param's value
+func readValueFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	return strings.TrimSuffix(s, "\r"), nil
+}
+
+// fileParam returns whether the param's value may be read from a file, see
+// NewSourceFiles and EnvFiles. Positional, boolean, list and map params can't
+// be.
+func fileParam(p Param) bool {
+	_, isList := listParamTypes[p.ParamType]
+	return p.Positional == 0 && !isList &&
+		p.ParamType != ParamTypeBool && p.ParamType != ParamTypeStringMap
+}
+
+type sourceFiles struct {
+	innerSrc Source
+}
+
+// NewSourceFiles wraps an existing Source so that the value of a param may
+// instead be given as the path of a file to read it from, e.g. a secret mounted
+// by Docker or Kubernetes. For each param a "-file" param is added, so
+// "db-password" can be given by "--db-password-file" on the command line or by
+// DB_PASSWORD_FILE in the environment. The contents of the file, with any
+// trailing newline removed, are used as the param's value.
+//
+// A value given directly for a param by the inner Source takes precedence over
+// one read from a file. It's an error for the file to not exist or not be
+// readable.
+//
+// Positional, boolean, list and map params can't be read from a file, nor can
+// params for which a param named "<name>-file" already exists.
+func NewSourceFiles(inner Source) Source {
+	return sourceFiles{innerSrc: inner}
+}
+
+func (sf sourceFiles) Parse(pp []Param) (map[string]string, error) {
+	vals, _, err := sf.parseSet(detachedState(), pp)
+	if err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+func (sf sourceFiles) String() string {
+	return "file"
+}
+
+func (sf sourceFiles) innerSources() []Source {
+	return []Source{sf.innerSrc}
+}
+
+func (sf sourceFiles) parseSet(st *parseState, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	names := make(map[string]bool, len(pp))
+	for _, p := range pp {
+		names[p.Name] = true
+	}
+
+	// fileNames maps each "-file" param to the param it's for
+	fileNames := map[string]string{}
+	all := append(make([]Param, 0, len(pp)*2), pp...)
+	for _, p := range pp {
+		fileName := p.Name + "-file"
+		if !fileParam(p) || names[fileName] {
+			continue
+		}
+		fileNames[fileName] = p.Name
+		all = append(all, Param{
+			ParamType: ParamTypeString,
+			Name:      fileName,
+			Usage:     fmt.Sprintf("Name of file to read the value of %s from. %s takes precedence", p.Name, p.Name),
+		})
+	}
+
+	vals, origins, err := st.parseSource(sf.innerSrc, all)
+	var errs Errors
+	if err != nil {
+		errs = errs.append(err)
+	}
+
+	for fileName, name := range fileNames {
+		path, ok := vals[fileName]
+		origin := origins[fileName]
+		delete(vals, fileName)
+		delete(origins, fileName)
+		if !ok || path == "" {
+			continue
+		} else if _, ok := vals[name]; ok {
+			continue
+		}
+
+		val, err := readValueFile(path)
+		if err != nil {
+			errs = append(errs, &Error{
+				Param:  name,
+				Source: origin.Source,
+				Err:    fmt.Errorf("reading %s: %w", fileName, err),
+			})
+			continue
+		}
+		origin.File = path
+		vals[name], origins[name] = val, origin
+	}
+
+	if len(errs) > 0 {
+		return vals, origins, errs
+	}
+	return vals, origins, nil
+}
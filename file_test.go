@@ -0,0 +1,82 @@
+package lflag
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceFiles(t *T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("hunter2\n"), 0600))
+
+	pp := []Param{
+		{ParamType: ParamTypeString, Name: "db-password"},
+		{ParamType: ParamTypeString, Name: "api-key"},
+		{ParamType: ParamTypeString, Name: "cert"},
+		{ParamType: ParamTypeString, Name: "cert-file"},
+		{ParamType: ParamTypeBool, Name: "verbose"},
+	}
+
+	st := detachedState()
+	vals, origins, err := sourceFiles{innerSrc: Sources{
+		SourceStub{"db-password-file": secret, "api-key-file": secret},
+		sourceCLI{testArgs: []string{"--api-key", "direct", "--cert-file", "cert.pem"}},
+	}}.parseSet(st, pp)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"db-password": "hunter2",
+		"api-key":     "direct",
+		"cert-file":   "cert.pem",
+	}, vals)
+	assert.Equal(t, map[string]ParamOrigin{
+		"db-password": {Source: "stub", File: secret},
+		"api-key":     {Source: "cli", Key: "--api-key", Arg: 1},
+		"cert-file":   {Source: "cli", Key: "--cert-file", Arg: 3},
+	}, origins)
+
+	_, _, err = sourceFiles{innerSrc: SourceStub{
+		"db-password-file": filepath.Join(dir, "missing"),
+	}}.parseSet(st, pp)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Contains(t, err.Error(), `parameter "db-password" (from stub): reading db-password-file: open `)
+}
+
+func TestSourceEnvFiles(t *T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("hunter2\r\n"), 0600))
+
+	pp := []Param{
+		{ParamType: ParamTypeString, Name: "db-password"},
+		{ParamType: ParamTypeString, Name: "api-key"},
+		{ParamType: ParamTypeStringSlice, Name: "hosts"},
+	}
+
+	se := NewSourceEnv(EnvFiles()).(sourceEnv)
+	out, _, err := se.parseEnv([]string{
+		"DB_PASSWORD_FILE=" + secret,
+		"API_KEY_FILE=" + secret,
+		"API_KEY=direct",
+		"HOSTS_FILE=" + secret,
+	}, pp)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"db-password": "hunter2",
+		"api-key":     "direct",
+	}, out)
+
+	_, _, err = se.parseEnv([]string{"DB_PASSWORD_FILE=" + filepath.Join(dir, "missing")}, pp)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Contains(t, err.Error(), `parameter "db-password": reading DB_PASSWORD_FILE: open `)
+
+	out, _, err = NewSourceEnv().(sourceEnv).parseEnv([]string{"DB_PASSWORD_FILE=" + secret}, pp)
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
@@ -1,8 +1,16 @@
 module github.com/levenlabs/go-lflag
 
-go 1.15
+go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/levenlabs/go-llog v1.0.0
 	github.com/stretchr/testify v1.7.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/levenlabs/errctx v1.0.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 )
@@ -0,0 +1,203 @@
+package lflag
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type sourceINI struct {
+	innerSrc    Source
+	testINIFile io.Reader // used as a fake ini file in tests
+}
+
+// NewSourceINI wraps an existing Source and adds support for reading an ini
+// file to source parameter values. The values coming from the inner Source will
+// overwrite any which are found in the ini file.
+//
+// The file is named by the config-ini-file param, which may be given multiple
+// times, e.g. a base file followed by an environment's overlay, with values in
+// later files overwriting earlier ones. It may also name a directory, in which
+// case the *.ini files within it are read in lexical order.
+//
+// The keys within a section are prefixed by the section's name, like Prefixed,
+// so "addr" within "[db]" is the value of the "db-addr" param, and a section
+// named "[db.replica]" is nested within "[db]". Keys and section names are
+// case-insensitive, and lines beginning with ';' or '#' are comments. Values
+// may be surrounded by quotes, and a key may be separated from its value by
+// either '=' or ':'.
+//
+// The file may include others by giving their paths, relative to its own
+// directory, using the "$include" key, which may be repeated. Those are read
+// before the file itself, so that it overwrites them.
+//
+// List params (e.g. StringSlice) are given by repeating the key once for each
+// element, and map params (e.g. StringMap) as a section of their own. For other
+// params a repeated key's last value is used.
+func NewSourceINI(inner Source) Source {
+	return sourceINI{innerSrc: inner}
+}
+
+func (si sourceINI) Parse(pp []Param) (map[string]string, error) {
+	vals, _, err := si.parseSet(detachedState(), pp)
+	if err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+func (si sourceINI) String() string {
+	return "ini"
+}
+
+func (si sourceINI) innerSources() []Source {
+	return []Source{si.innerSrc}
+}
+
+func (si sourceINI) parseSet(st *parseState, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	return configFile{
+		src:      si,
+		inner:    si.innerSrc,
+		name:     "config-ini-file",
+		kind:     "ini",
+		exts:     []string{".ini"},
+		testFile: si.testINIFile,
+		parse:    si.parseFile,
+	}.parseSet(st, pp)
+}
+
+// parseFile decodes the given ini file and returns the values found in it for
+// the given params
+func (si sourceINI) parseFile(r io.Reader, pp []Param) (map[string]string, map[string]string, []string, error) {
+	tree, err := parseINI(r)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	includes, err := configIncludes(tree[includeKey])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	delete(tree, includeKey)
+
+	vals, keys, err := flattenConfig(tree, pp, iniString, configTable)
+	return vals, keys, includes, err
+}
+
+// parseINI decodes an ini file into a tree of values, see flattenConfig. A key
+// which is given more than once has all of its values in a []interface{}.
+func parseINI(r io.Reader) (map[string]interface{}, error) {
+	tree := map[string]interface{}{}
+	section := tree
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" || l[0] == ';' || l[0] == '#' {
+			continue
+		}
+
+		if l[0] == '[' {
+			if l[len(l)-1] != ']' {
+				return nil, fmt.Errorf("line %d: malformed section %q", line, l)
+			}
+			section = tree
+			for _, name := range strings.Split(l[1:len(l)-1], ".") {
+				name = strings.ToLower(strings.TrimSpace(name))
+				sub, ok := section[name].(map[string]interface{})
+				if _, exists := section[name]; exists && !ok {
+					return nil, fmt.Errorf("line %d: section %q is also a key", line, name)
+				} else if !ok {
+					sub = map[string]interface{}{}
+					section[name] = sub
+				}
+				section = sub
+			}
+			continue
+		}
+
+		i := strings.IndexAny(l, "=:")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: malformed line %q", line, l)
+		}
+		k := strings.ToLower(strings.TrimSpace(l[:i]))
+		v := iniUnquote(strings.TrimSpace(l[i+1:]))
+		switch prev := section[k].(type) {
+		case nil:
+			section[k] = v
+		case string:
+			section[k] = []interface{}{prev, v}
+		case []interface{}:
+			section[k] = append(prev, v)
+		default:
+			return nil, fmt.Errorf("line %d: key %q is also a section", line, k)
+		}
+	}
+	return tree, sc.Err()
+}
+
+// iniUnquote removes the quotes surrounding an ini value, if there are any
+func iniUnquote(v string) string {
+	if len(v) < 2 || v[0] != v[len(v)-1] {
+		return v
+	} else if v[0] == '"' {
+		if str, err := strconv.Unquote(v); err == nil {
+			return str
+		}
+		return v[1 : len(v)-1]
+	} else if v[0] == '\'' {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// iniString converts a value decoded from an ini file into the string form for
+// the given param
+func iniString(p Param, v interface{}) (string, error) {
+	if _, ok := listParamTypes[p.ParamType]; ok {
+		switch v := v.(type) {
+		case string:
+			if v == "" {
+				return joinListKeepEmpty(nil), nil
+			}
+			return joinList([]string{v}), nil
+		case []interface{}:
+			strs := make([]string, len(v))
+			for i := range v {
+				strs[i] = v[i].(string)
+			}
+			return joinList(strs), nil
+		}
+		return "", errors.New("expected a key, not a section")
+	}
+
+	sm, isSection := v.(map[string]interface{})
+	if !isSection {
+		// a repeated key gives a list, of which the last is used
+		str, ok := v.(string)
+		if !ok {
+			vv := v.([]interface{})
+			str = vv[len(vv)-1].(string)
+		}
+
+		if p.ParamType == ParamTypeStringMap {
+			return "", errors.New("expected a section")
+		} else if p.ParamType == ParamTypeBool {
+			return normalizeBool(str)
+		}
+		return str, nil
+	} else if p.ParamType != ParamTypeStringMap {
+		return "", errors.New("expected a key, not a section")
+	}
+	m := make(map[string]string, len(sm))
+	for k, elem := range sm {
+		str, ok := elem.(string)
+		if !ok {
+			return "", fmt.Errorf("expected a single value for %q", k)
+		}
+		m[k] = str
+	}
+	return joinMapKeepEmpty(m), nil
+}
@@ -0,0 +1,97 @@
+package lflag
+
+import (
+	"bytes"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceINIBool(t *T) {
+	pp := []Param{{ParamType: ParamTypeBool, Name: "enabled"}}
+	for in, exp := range map[string]string{
+		"true": "true", "False": "false", "no": "false", "off": "false",
+		"0": "false", "1": "true", "on": "true",
+	} {
+		m, err := sourceINI{innerSrc: SourceStub{}, testINIFile: bytes.NewBufferString(
+			"enabled = " + in,
+		)}.Parse(pp)
+		assert.NoError(t, err, in)
+		assert.Equal(t, map[string]string{"enabled": exp}, m, in)
+	}
+
+	_, err := sourceINI{innerSrc: SourceStub{}, testINIFile: bytes.NewBufferString(
+		"enabled = maybe",
+	)}.Parse(pp)
+	assert.EqualError(t, err, `parameter "enabled" (from ini): "maybe" is not a valid boolean`)
+}
+
+func TestSourceINI(t *T) {
+	pp := []Param{
+		{ParamType: ParamTypeString, Name: "str"},
+		{ParamType: ParamTypeString, Name: "str2"},
+		{ParamType: ParamTypeString, Name: "str3"},
+		{ParamType: ParamTypeInt, Name: "int"},
+		{ParamType: ParamTypeBool, Name: "bool"},
+		{ParamType: ParamTypeStringSlice, Name: "strs"},
+		{ParamType: ParamTypeIntSlice, Name: "ints"},
+		{ParamType: ParamTypeStringMap, Name: "labels"},
+		{ParamType: ParamTypeString, Name: "db-addr"},
+		{ParamType: ParamTypeInt, Name: "db-replica-port"},
+	}
+
+	ts := SourceStub{
+		"str2": "bar", // this should overwrite the one in the iniFile
+	}
+
+	iniFile := bytes.NewBufferString(`
+; a comment
+str = "foo\nsomething"
+str2 = broken
+STR3: 'single'
+int = 1
+int = 2
+bool = true
+# another comment
+strs = a
+strs = b
+ints =
+
+[labels]
+env = prod
+
+[DB]
+addr = localhost:5432
+
+[db.replica]
+port = 5433
+`)
+
+	m, err := sourceINI{innerSrc: ts, testINIFile: iniFile}.Parse(pp)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"str":             "foo\nsomething",
+		"str2":            "bar",
+		"str3":            "single",
+		"int":             "2",
+		"bool":            "true",
+		"strs":            `["a","b"]`,
+		"ints":            "[]",
+		"labels":          `{"env":"prod"}`,
+		"db-addr":         "localhost:5432",
+		"db-replica-port": "5433",
+	}, m)
+
+	_, err = sourceINI{innerSrc: SourceStub{}, testINIFile: bytes.NewBufferString(`
+labels = foo
+[str]
+`)}.Parse(pp)
+	assert.EqualError(t, err, `2 configuration errors:
+	parameter "labels" (from ini): expected a section
+	parameter "str" (from ini): expected a key, not a section`)
+
+	_, err = sourceINI{innerSrc: SourceStub{}, testINIFile: bytes.NewBufferString(`
+[db
+`)}.Parse(pp)
+	assert.EqualError(t, err, `ini: line 2: malformed section "[db"`)
+}
@@ -3,8 +3,6 @@ package lflag
 import (
 	"encoding/json"
 	"io"
-	"os"
-	"strings"
 )
 
 type sourceJSON struct {
@@ -14,71 +12,105 @@ type sourceJSON struct {
 
 // NewSourceJSON wraps an existing Source and adds support for reading a json
 // file to source parameter values. The values coming from the inner Source will
-// overwrite any which are found in the json file
+// overwrite any which are found in the json file.
+//
+// The file is named by the config-json-file param, which may be given multiple
+// times, e.g. a base file followed by an environment's overlay, with values in
+// later files overwriting earlier ones. It may also name a directory, in which
+// case the *.json files within it are read in lexical order.
+//
+// The file may include others by giving their paths, relative to its own
+// directory, under the "$include" key, e.g. {"$include": ["base.json"]}. Those
+// are read before the file itself, so that it overwrites them.
+//
+// Keys within a nested object are prefixed by the object's key, like Prefixed,
+// so {"db": {"addr": "..."}} is the value of the "db-addr" param. Flat keys
+// like "db-addr" may also be used, but it's an error for a param to be given
+// by both.
+//
+// List params (e.g. StringSlice) are given as json arrays in the file, and map
+// params (e.g. StringMap) as json objects.
 func NewSourceJSON(inner Source) Source {
 	return sourceJSON{innerSrc: inner}
 }
 
 func (sj sourceJSON) Parse(pp []Param) (map[string]string, error) {
-	const paramName = "config-json-file"
-	pp = append(pp, Param{
-		ParamType: ParamTypeString,
-		Name:      paramName,
-		Usage:     "Name of json file to parse config object out of. Environment and CLI params overwrite json ones",
-	})
-
-	m, err := sj.innerSrc.Parse(pp)
+	vals, _, err := sj.parseSet(detachedState(), pp)
 	if err != nil {
 		return nil, err
 	}
+	return vals, nil
+}
 
-	// if the parsed m contains a config file set, or a test one is given, make
-	// a json decoder out of that. otherwise return the m we have
-	var dec *json.Decoder
-	if jsonConfigFile := m[paramName]; jsonConfigFile != "" {
-		f, err := os.Open(jsonConfigFile)
-		if err != nil {
-			return nil, err
-		}
-		defer f.Close()
-		dec = json.NewDecoder(f)
-	} else if sj.testJSONFile != nil {
-		dec = json.NewDecoder(sj.testJSONFile)
-	} else {
-		return m, nil
-	}
+func (sj sourceJSON) String() string {
+	return "json"
+}
 
+func (sj sourceJSON) innerSources() []Source {
+	return []Source{sj.innerSrc}
+}
+
+func (sj sourceJSON) parseSet(st *parseState, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	return configFile{
+		src:      sj,
+		inner:    sj.innerSrc,
+		name:     "config-json-file",
+		kind:     "json",
+		exts:     []string{".json"},
+		testFile: sj.testJSONFile,
+		parse:    sj.parseFile,
+	}.parseSet(st, pp)
+}
+
+// parseFile decodes the given json file and returns the values found in it for
+// the given params
+func (sj sourceJSON) parseFile(r io.Reader, pp []Param) (map[string]string, map[string]string, []string, error) {
 	// parse into a json map
 	var jm map[string]json.RawMessage
-	if err := dec.Decode(&jm); err != nil {
-		return nil, err
+	if err := json.NewDecoder(r).Decode(&jm); err != nil {
+		return nil, nil, nil, err
 	}
 
-	// now transform the map[string]json.RawMessage into a map[string]string
-	// using the json stringers
-	out := make(map[string]string, len(jm))
-	for _, p := range pp {
-		// we treat null and unset as the same thing
-		j, ok := jm[strings.ToLower(p.Name)]
-		if !ok {
-			continue
-		}
-		if string(j) == "null" {
-			continue
+	tree := jsonTree(jm)
+	var inc interface{}
+	if j, ok := tree[includeKey]; ok {
+		if err := json.Unmarshal(j.(json.RawMessage), &inc); err != nil {
+			return nil, nil, nil, err
 		}
+		delete(tree, includeKey)
+	}
+	includes, err := configIncludes(inc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
-		str, err := paramTypeJSONStringers[p.ParamType](j)
-		if err != nil {
-			return nil, err
-		}
-		out[p.Name] = str
+	// now transform the map[string]json.RawMessage into a map[string]string
+	// using the json stringers, flattening any nested objects
+	toString := func(p Param, v interface{}) (string, error) {
+		return jsonStringer(p.ParamType)(v.(json.RawMessage))
 	}
+	vals, keys, err := flattenConfig(tree, pp, toString, jsonTable)
+	return vals, keys, includes, err
+}
 
-	// merge m into out (so the inner source values overwrite this ones') and
-	// return that
-	for k, v := range m {
-		out[k] = v
+// jsonTree converts a decoded json object into a tree for flattenConfig. We
+// treat null and unset as the same thing, so null values are left out.
+func jsonTree(jm map[string]json.RawMessage) map[string]interface{} {
+	tree := make(map[string]interface{}, len(jm))
+	for k, j := range jm {
+		if string(j) != "null" {
+			tree[k] = j
+		}
 	}
+	return tree
+}
 
-	return out, nil
+// jsonTable is used as the table argument of flattenConfig for trees created
+// by jsonTree
+func jsonTable(v interface{}) map[string]interface{} {
+	var jm map[string]json.RawMessage
+	if err := json.Unmarshal(v.(json.RawMessage), &jm); err != nil {
+		return nil
+	}
+	return jsonTree(jm)
 }
@@ -12,9 +12,16 @@ func TestSourceJSON(t *T) {
 		{ParamType: ParamTypeString, Name: "str"},
 		{ParamType: ParamTypeString, Name: "str2"},
 		{ParamType: ParamTypeInt, Name: "int"},
+		{ParamType: ParamTypeInt64, Name: "int64"},
+		{ParamType: ParamTypeUint64, Name: "uint64"},
+		{ParamType: ParamTypeFloat64, Name: "float64"},
 		{ParamType: ParamTypeBool, Name: "bool"},
 		{ParamType: ParamTypeDuration, Name: "dur"},
 		{ParamType: ParamTypeJSON, Name: "json"},
+		{ParamType: ParamTypeStringSlice, Name: "strs"},
+		{ParamType: ParamTypeIntSlice, Name: "ints"},
+		{ParamType: ParamTypeDurationSlice, Name: "durs"},
+		{ParamType: ParamTypeStringMap, Name: "labels"},
 	}
 
 	ts := SourceStub{
@@ -25,19 +32,72 @@ func TestSourceJSON(t *T) {
 		"str": "foo\nsomething",
 		"str2": "broken",
 		"int":  1,
+		"int64": -9000000000,
+		"uint64": 18446744073709551615,
+		"float64": 1.5,
 		"bool": true,
 		"dur":  "30s",
-		"json": {"foo":"bar"}
+		"json": {"foo":"bar"},
+		"strs": ["a", "b"],
+		"ints": [1, 2],
+		"durs": [],
+		"labels": {"env": "prod", "shard": 1}
 	}`)
 
 	m, err := sourceJSON{innerSrc: ts, testJSONFile: jsonFile}.Parse(pp)
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]string{
-		"str":  "foo\nsomething",
-		"str2": "bar",
-		"int":  "1",
-		"bool": "true",
-		"dur":  "30s",
-		"json": `{"foo":"bar"}`,
+		"str":     "foo\nsomething",
+		"str2":    "bar",
+		"int":     "1",
+		"int64":   "-9000000000",
+		"uint64":  "18446744073709551615",
+		"float64": "1.5",
+		"bool":    "true",
+		"dur":     "30s",
+		"json":    `{"foo":"bar"}`,
+		"strs":    `["a","b"]`,
+		"ints":    `["1","2"]`,
+		"durs":    "[]",
+		"labels":  `{"env":"prod","shard":"1"}`,
+	}, m)
+}
+
+func TestSourceJSONNested(t *T) {
+	pp := []Param{
+		{ParamType: ParamTypeString, Name: "db-addr"},
+		{ParamType: ParamTypeInt, Name: "db-replica-port"},
+		{ParamType: ParamTypeStringMap, Name: "db-opts"},
+		{ParamType: ParamTypeString, Name: "log-level"},
+		{ParamType: ParamTypeString, Name: "name"},
+	}
+
+	jsonFile := bytes.NewBufferString(`{
+		"db": {
+			"addr": "localhost:5432",
+			"replica": {"port": 5433},
+			"opts": {"sslmode": "disable"}
+		},
+		"log-level": "info",
+		"name": null,
+		"other": {"name": "ignored"}
+	}`)
+
+	m, err := sourceJSON{innerSrc: SourceStub{}, testJSONFile: jsonFile}.Parse(pp)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"db-addr":         "localhost:5432",
+		"db-replica-port": "5433",
+		"db-opts":         `{"sslmode":"disable"}`,
+		"log-level":       "info",
 	}, m)
+
+	jsonFile = bytes.NewBufferString(`{
+		"db": {"addr": "nested"},
+		"db-addr": "flat",
+		"log": {"level": null},
+		"log-level": "info"
+	}`)
+	_, err = sourceJSON{innerSrc: SourceStub{}, testJSONFile: jsonFile}.Parse(pp)
+	assert.EqualError(t, err, `parameter "db-addr" (from json): given by both "db.addr" and "db-addr"`)
 }
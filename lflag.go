@@ -13,6 +13,16 @@
 //	lflag.Parse(lflag.NewSourceCLI())
 //	db, err := NewDB(*addr, *poolSize)
 //
+// Params are defined on a Set, which also holds the functions passed into Do.
+// The package-level functions all use the CommandLine Set, but independent Sets
+// can be made with NewSet, e.g. for libraries or tests which want their own
+// configuration space.
+//
+// Validation can be attached to a param when it is defined, using
+// ParamOptions. Any value which fails validation causes Parse to fail.
+//
+//	poolSize := lflag.Int("db-pool-size", 10, "Number of connections", lflag.Min(1))
+//
 // # Sources
 //
 // lflag supports multiple Sources, which are places from which configuration
@@ -45,36 +55,14 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	llog "github.com/levenlabs/go-llog"
 )
 
-var (
-	m = map[string]param{}
-	l sync.Mutex
-
-	// queueCh is used to queue up future Do's
-	queueCh = make(chan func())
-
-	// doneCh is closed once spin is done and all queued up Do's have been called
-	// this is used to signal to future Do's that they shouldn't queue
-	doneCh = make(chan bool)
-
-	// callCh is used by Parse to get all queued up Do's
-	callCh = make(chan func())
-
-	// spinWg is only needed to keep track of spin for Reset to know when its
-	// safe to reset
-	spinWg sync.WaitGroup
-)
-
 func init() {
-	spinWg.Add(1)
-	go spin()
-
-	logLevel := String("log-level", "info", "Log level to run with. Available levels are: debug, info, warn, error, fatal")
+	// log levels were always case-insensitive, as llog upper-cases them
+	logLevel := Enum("log-level", "info", []string{"debug", "info", "warn", "error", "fatal"}, "Log level to run with", IgnoreCase())
 	Do(func() {
 		err := llog.SetLevelFromString(*logLevel)
 		if err != nil {
@@ -83,31 +71,6 @@ func init() {
 	})
 }
 
-func spin() {
-	defer spinWg.Done()
-	dos := []func(){
-		// seed this with an empty one so the for loop doesn't immediately break
-		func() {},
-	}
-loop:
-	for len(dos) > 0 {
-		select {
-		case fn, ok := <-queueCh:
-			// only Reset closes queueCh and so we should bail immediately
-			if !ok {
-				break loop
-			}
-			dos = append(dos, fn)
-		case callCh <- dos[0]:
-			dos = dos[1:]
-		}
-	}
-	// trigger all waiting/future Do calls to immediately happen
-	close(doneCh)
-	// tell Parse to stop looping
-	close(callCh)
-}
-
 func printfAndExit(str string, args ...interface{}) {
 	fmt.Printf(str, args...)
 	os.Stdout.Sync()
@@ -115,6 +78,13 @@ func printfAndExit(str string, args ...interface{}) {
 	os.Exit(0)
 }
 
+func errorAndExit(err error) {
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+	os.Stdout.Sync()
+	os.Stderr.Sync()
+	os.Exit(1)
+}
+
 // Prefixed is basically strings.Join except it ignores empty strings
 func Prefixed(strs ...string) string {
 	var nonemptys []string
@@ -127,87 +97,89 @@ func Prefixed(strs ...string) string {
 	return strings.Join(nonemptys, "-")
 }
 
-type param struct {
-	Param
-	ptr interface{}
+// String takes in the name of a config param, a default value, and a string
+// describing the usage for the param, and returns a pointer which will be
+// filled when Parse is called
+func (s *Set) String(name, value, usage string, opts ...ParamOption) *string {
+	p := Param{
+		ParamType: ParamTypeString,
+		Name:      name,
+		Default:   value,
+		Usage:     usage,
+	}
+	ptr := new(string)
+	return s.newParam(p, ptr, opts...).(*string)
 }
 
-func newParam(p Param, ptr interface{}) interface{} {
-	l.Lock()
-	defer l.Unlock()
-
-	pp, ok := m[p.Name]
-	if ok && pp.Param != p {
-		panic(fmt.Sprintf("param named %q already exists and differs from this new one", p.Name))
-	} else if !ok {
-		pp.Param = p
-		pp.ptr = ptr
-	}
-	m[p.Name] = pp
-	return pp.ptr
+// String calls String on CommandLine
+func String(name, value, usage string, opts ...ParamOption) *string {
+	return CommandLine.String(name, value, usage, opts...)
 }
 
-// Do registers the given function to be performed after Parse is called an all
-// param pointers have been filled in. Multiple functions may be registered
-// using Do, though the order they are called is guaranteed to be in calling
-// order. Once Parse has been called and ALL queued Do functions have returned,
-// sent function is immediately invoked. If Do is called within another Do, then
-// the sent function is added to the queue at the end and will be envoked once all
-// queued up Do's are called.
-func Do(fn func()) {
-	select {
-	// if doneCh is closed then we immediately call, otherwise we add it to the queue
-	case <-doneCh:
-		fn()
-	case queueCh <- fn:
+// RequiredString is like String, but it has no default value and must e set
+func (s *Set) RequiredString(name, usage string, opts ...ParamOption) *string {
+	p := Param{
+		ParamType: ParamTypeString,
+		Name:      name,
+		Usage:     usage,
+		Required:  true,
 	}
+	ptr := new(string)
+	return s.newParam(p, ptr, opts...).(*string)
 }
 
-// Reset removes any configured flags and resets everything back to before
-// Parse was called. This should ONLY be used in tests
-func Reset() {
-	// closing queueCh will immediately kill spin
-	close(queueCh)
-	spinWg.Wait()
-	m = map[string]param{}
-	queueCh = make(chan func())
-	doneCh = make(chan bool)
-	callCh = make(chan func())
-
-	spinWg.Add(1)
-	go spin()
+// RequiredString calls RequiredString on CommandLine
+func RequiredString(name, usage string, opts ...ParamOption) *string {
+	return CommandLine.RequiredString(name, usage, opts...)
 }
 
-// String takes in the name of a config param, a default value, and a string
-// describing the usage for the param, and returns a pointer which will be
-// filled when Parse is called
-func String(name, value, usage string) *string {
+// Enum is like String, but the value must be one of the given choices, or Parse
+// will return an error. The choices are also listed in the help string, and
+// completed by the script printed by --completion, see NewSourceCLI.
+//
+// If value isn't one of the choices this will panic
+func (s *Set) Enum(name, value string, choices []string, usage string, opts ...ParamOption) *string {
+	if checkChoices(Param{Choices: choices}, value) != nil {
+		panic(fmt.Sprintf("default %q of param %q isn't one of its choices", value, name))
+	}
 	p := Param{
 		ParamType: ParamTypeString,
 		Name:      name,
 		Default:   value,
 		Usage:     usage,
+		Choices:   choices,
 	}
 	ptr := new(string)
-	return newParam(p, ptr).(*string)
+	return s.newParam(p, ptr, opts...).(*string)
 }
 
-// RequiredString is like String, but it has no default value and must e set
-func RequiredString(name, usage string) *string {
+// Enum calls Enum on CommandLine
+func Enum(name, value string, choices []string, usage string, opts ...ParamOption) *string {
+	return CommandLine.Enum(name, value, choices, usage, opts...)
+}
+
+// RequiredEnum is like Enum, but it has no default value and must be set
+func (s *Set) RequiredEnum(name string, choices []string, usage string, opts ...ParamOption) *string {
 	p := Param{
 		ParamType: ParamTypeString,
 		Name:      name,
 		Usage:     usage,
 		Required:  true,
+		Choices:   choices,
 	}
 	ptr := new(string)
-	return newParam(p, ptr).(*string)
+	return s.newParam(p, ptr, opts...).(*string)
+}
+
+// RequiredEnum calls RequiredEnum on CommandLine
+func RequiredEnum(name string, choices []string, usage string, opts ...ParamOption) *string {
+	return CommandLine.RequiredEnum(name, choices, usage, opts...)
 }
 
 // Int takes in the name of a config param, a default value, and a string
 // describing the usage for the param, and returns a pointer which will be
 // filled when Parse is called
-func Int(name string, value int, usage string) *int {
+func (s *Set) Int(name string, value int, usage string, opts ...ParamOption) *int {
 	p := Param{
 		ParamType: ParamTypeInt,
 		Name:      name,
@@ -215,11 +187,16 @@ func Int(name string, value int, usage string) *int {
 		Usage:     usage,
 	}
 	ptr := new(int)
-	return newParam(p, ptr).(*int)
+	return s.newParam(p, ptr, opts...).(*int)
+}
+
+// Int calls Int on CommandLine
+func Int(name string, value int, usage string, opts ...ParamOption) *int {
+	return CommandLine.Int(name, value, usage, opts...)
 }
 
 // RequiredInt is like Int, but it has no default value and must e set
-func RequiredInt(name, usage string) *int {
+func (s *Set) RequiredInt(name, usage string, opts ...ParamOption) *int {
 	p := Param{
 		ParamType: ParamTypeInt,
 		Name:      name,
@@ -227,13 +204,162 @@ func RequiredInt(name, usage string) *int {
 		Required:  true,
 	}
 	ptr := new(int)
-	return newParam(p, ptr).(*int)
+	return s.newParam(p, ptr, opts...).(*int)
+}
+
+// RequiredInt calls RequiredInt on CommandLine
+func RequiredInt(name, usage string, opts ...ParamOption) *int {
+	return CommandLine.RequiredInt(name, usage, opts...)
+}
+
+// Int64 takes in the name of a config param, a default value, and a string
+// describing the usage for the param, and returns a pointer which will be
+// filled when Parse is called
+func (s *Set) Int64(name string, value int64, usage string, opts ...ParamOption) *int64 {
+	p := Param{
+		ParamType: ParamTypeInt64,
+		Name:      name,
+		Default:   strconv.FormatInt(value, 10),
+		Usage:     usage,
+	}
+	ptr := new(int64)
+	return s.newParam(p, ptr, opts...).(*int64)
+}
+
+// Int64 calls Int64 on CommandLine
+func Int64(name string, value int64, usage string, opts ...ParamOption) *int64 {
+	return CommandLine.Int64(name, value, usage, opts...)
+}
+
+// RequiredInt64 is like Int64, but it has no default value and must be set
+func (s *Set) RequiredInt64(name, usage string, opts ...ParamOption) *int64 {
+	p := Param{
+		ParamType: ParamTypeInt64,
+		Name:      name,
+		Usage:     usage,
+		Required:  true,
+	}
+	ptr := new(int64)
+	return s.newParam(p, ptr, opts...).(*int64)
+}
+
+// RequiredInt64 calls RequiredInt64 on CommandLine
+func RequiredInt64(name, usage string, opts ...ParamOption) *int64 {
+	return CommandLine.RequiredInt64(name, usage, opts...)
+}
+
+// Uint takes in the name of a config param, a default value, and a string
+// describing the usage for the param, and returns a pointer which will be
+// filled when Parse is called
+func (s *Set) Uint(name string, value uint, usage string, opts ...ParamOption) *uint {
+	p := Param{
+		ParamType: ParamTypeUint,
+		Name:      name,
+		Default:   strconv.FormatUint(uint64(value), 10),
+		Usage:     usage,
+	}
+	ptr := new(uint)
+	return s.newParam(p, ptr, opts...).(*uint)
+}
+
+// Uint calls Uint on CommandLine
+func Uint(name string, value uint, usage string, opts ...ParamOption) *uint {
+	return CommandLine.Uint(name, value, usage, opts...)
+}
+
+// RequiredUint is like Uint, but it has no default value and must be set
+func (s *Set) RequiredUint(name, usage string, opts ...ParamOption) *uint {
+	p := Param{
+		ParamType: ParamTypeUint,
+		Name:      name,
+		Usage:     usage,
+		Required:  true,
+	}
+	ptr := new(uint)
+	return s.newParam(p, ptr, opts...).(*uint)
+}
+
+// RequiredUint calls RequiredUint on CommandLine
+func RequiredUint(name, usage string, opts ...ParamOption) *uint {
+	return CommandLine.RequiredUint(name, usage, opts...)
+}
+
+// Uint64 takes in the name of a config param, a default value, and a string
+// describing the usage for the param, and returns a pointer which will be
+// filled when Parse is called
+func (s *Set) Uint64(name string, value uint64, usage string, opts ...ParamOption) *uint64 {
+	p := Param{
+		ParamType: ParamTypeUint64,
+		Name:      name,
+		Default:   strconv.FormatUint(value, 10),
+		Usage:     usage,
+	}
+	ptr := new(uint64)
+	return s.newParam(p, ptr, opts...).(*uint64)
+}
+
+// Uint64 calls Uint64 on CommandLine
+func Uint64(name string, value uint64, usage string, opts ...ParamOption) *uint64 {
+	return CommandLine.Uint64(name, value, usage, opts...)
+}
+
+// RequiredUint64 is like Uint64, but it has no default value and must be set
+func (s *Set) RequiredUint64(name, usage string, opts ...ParamOption) *uint64 {
+	p := Param{
+		ParamType: ParamTypeUint64,
+		Name:      name,
+		Usage:     usage,
+		Required:  true,
+	}
+	ptr := new(uint64)
+	return s.newParam(p, ptr, opts...).(*uint64)
+}
+
+// RequiredUint64 calls RequiredUint64 on CommandLine
+func RequiredUint64(name, usage string, opts ...ParamOption) *uint64 {
+	return CommandLine.RequiredUint64(name, usage, opts...)
+}
+
+// Float64 takes in the name of a config param, a default value, and a string
+// describing the usage for the param, and returns a pointer which will be
+// filled when Parse is called
+func (s *Set) Float64(name string, value float64, usage string, opts ...ParamOption) *float64 {
+	p := Param{
+		ParamType: ParamTypeFloat64,
+		Name:      name,
+		Default:   strconv.FormatFloat(value, 'g', -1, 64),
+		Usage:     usage,
+	}
+	ptr := new(float64)
+	return s.newParam(p, ptr, opts...).(*float64)
+}
+
+// Float64 calls Float64 on CommandLine
+func Float64(name string, value float64, usage string, opts ...ParamOption) *float64 {
+	return CommandLine.Float64(name, value, usage, opts...)
+}
+
+// RequiredFloat64 is like Float64, but it has no default value and must be set
+func (s *Set) RequiredFloat64(name, usage string, opts ...ParamOption) *float64 {
+	p := Param{
+		ParamType: ParamTypeFloat64,
+		Name:      name,
+		Usage:     usage,
+		Required:  true,
+	}
+	ptr := new(float64)
+	return s.newParam(p, ptr, opts...).(*float64)
+}
+
+// RequiredFloat64 calls RequiredFloat64 on CommandLine
+func RequiredFloat64(name, usage string, opts ...ParamOption) *float64 {
+	return CommandLine.RequiredFloat64(name, usage, opts...)
 }
 
 // Bool takes in the name of a config param, a default value, and a string
 // describing the usage for the param, and returns a pointer which will be
 // filled when Parse is called
-func Bool(name string, value bool, usage string) *bool {
+func (s *Set) Bool(name string, value bool, usage string, opts ...ParamOption) *bool {
 	var def string
 	if value {
 		def = "true"
@@ -245,11 +371,16 @@ func Bool(name string, value bool, usage string) *bool {
 		Usage:     usage,
 	}
 	ptr := new(bool)
-	return newParam(p, ptr).(*bool)
+	return s.newParam(p, ptr, opts...).(*bool)
+}
+
+// Bool calls Bool on CommandLine
+func Bool(name string, value bool, usage string, opts ...ParamOption) *bool {
+	return CommandLine.Bool(name, value, usage, opts...)
 }
 
 // RequiredBool is like Bool, but it has no default value and must be set
-func RequiredBool(name, usage string) *bool {
+func (s *Set) RequiredBool(name, usage string, opts ...ParamOption) *bool {
 	p := Param{
 		ParamType: ParamTypeBool,
 		Name:      name,
@@ -257,7 +388,12 @@ func RequiredBool(name, usage string) *bool {
 		Required:  true,
 	}
 	ptr := new(bool)
-	return newParam(p, ptr).(*bool)
+	return s.newParam(p, ptr, opts...).(*bool)
+}
+
+// RequiredBool calls RequiredBool on CommandLine
+func RequiredBool(name, usage string, opts ...ParamOption) *bool {
+	return CommandLine.RequiredBool(name, usage, opts...)
 }
 
 // Duration takes in the name of a config param, a default value, a string
@@ -266,7 +402,7 @@ func RequiredBool(name, usage string) *bool {
 //
 // The value given by a config for a Duration must be parsable by
 // time.ParseDuration.
-func Duration(name string, value time.Duration, usage string) *time.Duration {
+func (s *Set) Duration(name string, value time.Duration, usage string, opts ...ParamOption) *time.Duration {
 	p := Param{
 		ParamType: ParamTypeDuration,
 		Name:      name,
@@ -274,12 +410,17 @@ func Duration(name string, value time.Duration, usage string) *time.Duration {
 		Usage:     usage,
 	}
 	ptr := new(time.Duration)
-	return newParam(p, ptr).(*time.Duration)
+	return s.newParam(p, ptr, opts...).(*time.Duration)
+}
+
+// Duration calls Duration on CommandLine
+func Duration(name string, value time.Duration, usage string, opts ...ParamOption) *time.Duration {
+	return CommandLine.Duration(name, value, usage, opts...)
 }
 
 // RequiredDuration is like Duration, but it has no default value and must be
 // set
-func RequiredDuration(name, usage string) *time.Duration {
+func (s *Set) RequiredDuration(name, usage string, opts ...ParamOption) *time.Duration {
 	p := Param{
 		ParamType: ParamTypeDuration,
 		Name:      name,
@@ -287,7 +428,173 @@ func RequiredDuration(name, usage string) *time.Duration {
 		Required:  true,
 	}
 	ptr := new(time.Duration)
-	return newParam(p, ptr).(*time.Duration)
+	return s.newParam(p, ptr, opts...).(*time.Duration)
+}
+
+// RequiredDuration calls RequiredDuration on CommandLine
+func RequiredDuration(name, usage string, opts ...ParamOption) *time.Duration {
+	return CommandLine.RequiredDuration(name, usage, opts...)
+}
+
+// StringSlice takes in the name of a config param, a default value, and a
+// string describing the usage for the param, and returns a pointer which will
+// be filled when Parse is called.
+//
+// On the command line the param may be given multiple times, each time adding
+// an element to the list. See NewSourceEnv and NewSourceJSON for how lists are
+// given in those Sources.
+func (s *Set) StringSlice(name string, value []string, usage string, opts ...ParamOption) *[]string {
+	p := Param{
+		ParamType: ParamTypeStringSlice,
+		Name:      name,
+		Default:   joinList(value),
+		Usage:     usage,
+	}
+	ptr := new([]string)
+	return s.newParam(p, ptr, opts...).(*[]string)
+}
+
+// StringSlice calls StringSlice on CommandLine
+func StringSlice(name string, value []string, usage string, opts ...ParamOption) *[]string {
+	return CommandLine.StringSlice(name, value, usage, opts...)
+}
+
+// RequiredStringSlice is like StringSlice, but it has no default value and must
+// be set
+func (s *Set) RequiredStringSlice(name, usage string, opts ...ParamOption) *[]string {
+	p := Param{
+		ParamType: ParamTypeStringSlice,
+		Name:      name,
+		Usage:     usage,
+		Required:  true,
+	}
+	ptr := new([]string)
+	return s.newParam(p, ptr, opts...).(*[]string)
+}
+
+// RequiredStringSlice calls RequiredStringSlice on CommandLine
+func RequiredStringSlice(name, usage string, opts ...ParamOption) *[]string {
+	return CommandLine.RequiredStringSlice(name, usage, opts...)
+}
+
+// IntSlice is like StringSlice, but each element must be parsable as an int
+func (s *Set) IntSlice(name string, value []int, usage string, opts ...ParamOption) *[]int {
+	strs := make([]string, len(value))
+	for i, v := range value {
+		strs[i] = strconv.Itoa(v)
+	}
+	p := Param{
+		ParamType: ParamTypeIntSlice,
+		Name:      name,
+		Default:   joinList(strs),
+		Usage:     usage,
+	}
+	ptr := new([]int)
+	return s.newParam(p, ptr, opts...).(*[]int)
+}
+
+// IntSlice calls IntSlice on CommandLine
+func IntSlice(name string, value []int, usage string, opts ...ParamOption) *[]int {
+	return CommandLine.IntSlice(name, value, usage, opts...)
+}
+
+// RequiredIntSlice is like IntSlice, but it has no default value and must be
+// set
+func (s *Set) RequiredIntSlice(name, usage string, opts ...ParamOption) *[]int {
+	p := Param{
+		ParamType: ParamTypeIntSlice,
+		Name:      name,
+		Usage:     usage,
+		Required:  true,
+	}
+	ptr := new([]int)
+	return s.newParam(p, ptr, opts...).(*[]int)
+}
+
+// RequiredIntSlice calls RequiredIntSlice on CommandLine
+func RequiredIntSlice(name, usage string, opts ...ParamOption) *[]int {
+	return CommandLine.RequiredIntSlice(name, usage, opts...)
+}
+
+// DurationSlice is like StringSlice, but each element must be parsable by
+// time.ParseDuration
+func (s *Set) DurationSlice(name string, value []time.Duration, usage string, opts ...ParamOption) *[]time.Duration {
+	strs := make([]string, len(value))
+	for i, v := range value {
+		strs[i] = v.String()
+	}
+	p := Param{
+		ParamType: ParamTypeDurationSlice,
+		Name:      name,
+		Default:   joinList(strs),
+		Usage:     usage,
+	}
+	ptr := new([]time.Duration)
+	return s.newParam(p, ptr, opts...).(*[]time.Duration)
+}
+
+// DurationSlice calls DurationSlice on CommandLine
+func DurationSlice(name string, value []time.Duration, usage string, opts ...ParamOption) *[]time.Duration {
+	return CommandLine.DurationSlice(name, value, usage, opts...)
+}
+
+// RequiredDurationSlice is like DurationSlice, but it has no default value and
+// must be set
+func (s *Set) RequiredDurationSlice(name, usage string, opts ...ParamOption) *[]time.Duration {
+	p := Param{
+		ParamType: ParamTypeDurationSlice,
+		Name:      name,
+		Usage:     usage,
+		Required:  true,
+	}
+	ptr := new([]time.Duration)
+	return s.newParam(p, ptr, opts...).(*[]time.Duration)
+}
+
+// RequiredDurationSlice calls RequiredDurationSlice on CommandLine
+func RequiredDurationSlice(name, usage string, opts ...ParamOption) *[]time.Duration {
+	return CommandLine.RequiredDurationSlice(name, usage, opts...)
+}
+
+// StringMap takes in the name of a config param, a default value, and a string
+// describing the usage for the param, and returns a pointer which will be
+// filled when Parse is called.
+//
+// On the command line the param is given as "key=value", and may be given
+// multiple times, each time adding a key to the map. See NewSourceEnv and
+// NewSourceJSON for how maps are given in those Sources.
+func (s *Set) StringMap(name string, value map[string]string, usage string, opts ...ParamOption) *map[string]string {
+	p := Param{
+		ParamType: ParamTypeStringMap,
+		Name:      name,
+		Default:   joinMap(value),
+		Usage:     usage,
+	}
+	ptr := new(map[string]string)
+	return s.newParam(p, ptr, opts...).(*map[string]string)
+}
+
+// StringMap calls StringMap on CommandLine
+func StringMap(name string, value map[string]string, usage string, opts ...ParamOption) *map[string]string {
+	return CommandLine.StringMap(name, value, usage, opts...)
+}
+
+// RequiredStringMap is like StringMap, but it has no default value and must be
+// set
+func (s *Set) RequiredStringMap(name, usage string, opts ...ParamOption) *map[string]string {
+	p := Param{
+		ParamType: ParamTypeStringMap,
+		Name:      name,
+		Usage:     usage,
+		Required:  true,
+	}
+	ptr := new(map[string]string)
+	return s.newParam(p, ptr, opts...).(*map[string]string)
+}
+
+// RequiredStringMap calls RequiredStringMap on CommandLine
+func RequiredStringMap(name, usage string, opts ...ParamOption) *map[string]string {
+	return CommandLine.RequiredStringMap(name, usage, opts...)
 }
 
 // Custom takes in a paramType, the name of a config param, a default value, a
@@ -299,8 +606,8 @@ func RequiredDuration(name, usage string) *time.Duration {
 //
 // You can use type-assertion on the return value to get the pointer type that
 // you expect. For instance, if your custom ParamType is for time.Time, this
-// would return a *time.Time.
-func Custom(paramType, name string, value interface{}, usage string) interface{} {
+// would return a *time.Time. See Define for a type-safe alternative.
+func (s *Set) Custom(paramType, name string, value interface{}, usage string, opts ...ParamOption) interface{} {
 	p := Param{
 		ParamType: paramType,
 		Name:      name,
@@ -312,11 +619,16 @@ func Custom(paramType, name string, value interface{}, usage string) interface{}
 		panic("lflag: ParamType not defined: " + paramType)
 	}
 	ptr := reflect.New(typ).Interface()
-	return newParam(p, ptr)
+	return s.newParam(p, ptr, opts...)
+}
+
+// Custom calls Custom on CommandLine
+func Custom(paramType, name string, value interface{}, usage string, opts ...ParamOption) interface{} {
+	return CommandLine.Custom(paramType, name, value, usage, opts...)
 }
 
 // RequiredCustom is like Custom, but it has no default and must be set
-func RequiredCustom(paramType, name, usage string) interface{} {
+func (s *Set) RequiredCustom(paramType, name, usage string, opts ...ParamOption) interface{} {
 	p := Param{
 		ParamType: paramType,
 		Name:      name,
@@ -328,7 +640,12 @@ func RequiredCustom(paramType, name, usage string) interface{} {
 		panic("lflag: ParamType not defined: " + paramType)
 	}
 	ptr := reflect.New(typ).Interface()
-	return newParam(p, ptr)
+	return s.newParam(p, ptr, opts...)
+}
+
+// RequiredCustom calls RequiredCustom on CommandLine
+func RequiredCustom(paramType, name, usage string, opts ...ParamOption) interface{} {
+	return CommandLine.RequiredCustom(paramType, name, usage, opts...)
 }
 
 // JSON reads in the config param as a string and json.Unmarshals it into the
@@ -336,7 +653,7 @@ func RequiredCustom(paramType, name, usage string) interface{} {
 // describes the parameter.
 //
 // If value cannot be json.Marshaled (for help string purposes) this will panic
-func JSON(rcv interface{}, name string, value interface{}, usage string) {
+func (s *Set) JSON(rcv interface{}, name string, value interface{}, usage string, opts ...ParamOption) {
 	jValue, err := json.Marshal(value)
 	if err != nil {
 		panic(err)
@@ -348,72 +665,62 @@ func JSON(rcv interface{}, name string, value interface{}, usage string) {
 		Default:   string(jValue),
 		Usage:     usage,
 	}
-	ptr := newParam(p, rcv)
+	ptr := s.newParam(p, rcv, opts...)
 	if ptr != rcv {
 		panic(fmt.Sprintf("param named %q already exists and differs from this new one", p.Name))
 	}
 }
 
+// JSON calls JSON on CommandLine
+func JSON(rcv interface{}, name string, value interface{}, usage string, opts ...ParamOption) {
+	CommandLine.JSON(rcv, name, value, usage, opts...)
+}
+
 // RequiredJSON is like JSON, but it has no default and must be set
-func RequiredJSON(rcv interface{}, name string, usage string) {
+func (s *Set) RequiredJSON(rcv interface{}, name string, usage string, opts ...ParamOption) {
 	p := Param{
 		ParamType: ParamTypeJSON,
 		Name:      name,
 		Usage:     usage,
 		Required:  true,
 	}
-	newParam(p, rcv)
+	s.newParam(p, rcv, opts...)
 }
 
-// Parse goes through each Source and compiles a set of values for registered
-// params (later Sources overwrite previous ones), fills in param pointers, then
-// calls all functions registered using Do.
-//
-// At the end of Parse all of the lflag package's params are reset. Any calls
-// to Do will immediately invoke the sent function after Parse is called.
-func Parse(s Source) {
-	l.Lock()
-	defer l.Unlock()
-
-	pp := make([]Param, 0, len(m))
-	for _, p := range m {
-		pp = append(pp, p.Param)
-	}
-
-	vals, err := s.Parse(pp)
-	if err != nil {
-		panic(err)
-	}
+// RequiredJSON calls RequiredJSON on CommandLine
+func RequiredJSON(rcv interface{}, name string, usage string, opts ...ParamOption) {
+	CommandLine.RequiredJSON(rcv, name, usage, opts...)
+}
 
-	for _, p := range pp {
-		val, valOk := vals[p.Name]
-		if !valOk {
-			if p.Required {
-				panic(fmt.Sprintf("parameter %q required but not set", p.Name))
-			}
-			val = p.Default
-		}
+// Do calls Do on CommandLine. See Set.Do.
+func Do(fn func()) {
+	CommandLine.Do(fn)
+}
 
-		err := paramTypeParsers[p.ParamType](val, m[p.Name].ptr)
-		if err != nil {
-			panic(fmt.Sprintf("error parsing parameter %s: %v", p.Name, err))
-		}
-	}
+// Reset calls Reset on CommandLine. This should ONLY be used in tests.
+func Reset() {
+	CommandLine.Reset()
+}
 
-	for fn := range callCh {
-		fn()
-	}
+// Parse calls Parse on CommandLine. See Set.Parse.
+func Parse(s Source) {
+	CommandLine.Parse(s)
+}
 
-	m = map[string]param{}
+// ParseE calls ParseE on CommandLine. See Set.ParseE.
+func ParseE(s Source) error {
+	return CommandLine.ParseE(s)
 }
 
-// Configure is a shortcut around Parse which uses our default sources (in order
-// of least-to-most precedent: json-file, environment, cli).
+// Configure calls Configure on CommandLine. See Set.Configure.
 //
 // Additionally, lflag.NotifyReadyAfter is called  and closed after Parse is
 // called letting any service manager know that we are now ready.
 func Configure() {
-	var s Source = Sources{NewSourceEnv(), NewSourceCLI()}
-	s = NewSourceJSON(s)
-	Parse(s)
+	CommandLine.Configure()
+}
+
+// ConfigureE calls ConfigureE on CommandLine. See Set.ConfigureE.
+func ConfigureE() error {
+	return CommandLine.ConfigureE()
 }
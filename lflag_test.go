@@ -1,6 +1,9 @@
 package lflag
 
 import (
+	"bytes"
+	"errors"
+	"math"
 	"sync"
 	"testing"
 	"time"
@@ -89,6 +92,248 @@ func TestParseRequired(t *testing.T) {
 	assert.Equal(t, jstruct{Foo: "FOO", Bar: 10}, j)
 }
 
+func TestParseE(t *testing.T) {
+	defer Reset()
+
+	RequiredString("str", "Some string")
+	err := ParseE(SourceStub{})
+	var lerr *Error
+	assert.True(t, errors.As(err, &lerr))
+	assert.Equal(t, "str", lerr.Param)
+	assert.True(t, errors.Is(err, ErrRequired))
+
+	Reset()
+	Int("int", 5, "Some int")
+	err = ParseE(SourceStub{"int": "five"})
+	assert.True(t, errors.As(err, &lerr))
+	assert.Equal(t, "int", lerr.Param)
+}
+
+func TestParseEAggregate(t *testing.T) {
+	defer Reset()
+
+	RequiredString("str", "Some string")
+	Int("int", 5, "Some int")
+	Duration("dur", time.Second, "Some duration")
+	err := ParseE(Sources{
+		SourceStub{"int": "five"},
+		sourceJSON{
+			innerSrc:     SourceStub{},
+			testJSONFile: bytes.NewBufferString(`{"dur": 5}`),
+		},
+	})
+
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+	assert.Equal(t, "dur", errs[0].Param)
+	assert.Equal(t, "json", errs[0].Source)
+	assert.Equal(t, "int", errs[1].Param)
+	assert.Equal(t, "stub", errs[1].Source)
+	assert.Equal(t, "str", errs[2].Param)
+	assert.True(t, errors.Is(errs[2], ErrRequired))
+
+	// these are what errors.Is and errors.As use before Go 1.20, which doesn't
+	// support Unwrap returning multiple errors
+	assert.True(t, errs.Is(ErrRequired))
+	assert.False(t, errs.Is(ErrUnknown))
+	var lerr *Error
+	assert.True(t, errs.As(&lerr))
+	assert.Equal(t, "dur", lerr.Param)
+}
+
+func TestParseNumeric(t *testing.T) {
+	s := NewSet()
+	i64 := s.Int64("int64", 5, "Some int64")
+	u := s.Uint("uint", 5, "Some uint")
+	u64 := s.RequiredUint64("uint64", "Some uint64")
+	f := s.Float64("float64", 0.5, "Some float64")
+	fDef := s.Float64("float64-default", 0.5, "Some float64")
+
+	err := s.ParseE(SourceStub{
+		"int64":   "-9223372036854775808",
+		"uint":    "8",
+		"uint64":  "18446744073709551615",
+		"float64": "1e3",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MinInt64), *i64)
+	assert.Equal(t, uint(8), *u)
+	assert.Equal(t, uint64(math.MaxUint64), *u64)
+	assert.Equal(t, 1000.0, *f)
+	assert.Equal(t, 0.5, *fDef)
+
+	s = NewSet()
+	s.Int64("int64", 5, "Some int64")
+	s.Uint("uint", 5, "Some uint")
+	err = s.ParseE(SourceStub{
+		"int64": "9223372036854775808",
+		"uint":  "-1",
+	})
+	assert.EqualError(t, err, `2 configuration errors:
+	parameter "int64" (from stub): "9223372036854775808" is out of range for type int64
+	parameter "uint" (from stub): "-1" is not a valid uint`)
+}
+
+func TestParseSlice(t *testing.T) {
+	s := NewSet()
+	strs := s.StringSlice("strs", []string{"a"}, "Some strings")
+	ints := s.IntSlice("ints", []int{1, 2}, "Some ints")
+	durs := s.RequiredDurationSlice("durs", "Some durations")
+	empty := s.StringSlice("empty", []string{"a"}, "Some strings")
+
+	err := s.ParseE(SourceStub{
+		"strs":  `["b","c"]`,
+		"durs":  `["1s","1m"]`,
+		"empty": "[]",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, *strs)
+	assert.Equal(t, []int{1, 2}, *ints)
+	assert.Equal(t, []time.Duration{time.Second, time.Minute}, *durs)
+	assert.Empty(t, *empty)
+
+	s = NewSet()
+	s.IntSlice("ints", nil, "Some ints")
+	err = s.ParseE(SourceStub{"ints": `["1","two"]`})
+	assert.EqualError(t, err, `parameter "ints" (from stub): "two" is not a valid int`)
+}
+
+func TestParseMap(t *testing.T) {
+	s := NewSet()
+	labels := s.StringMap("labels", map[string]string{"env": "dev"}, "Some labels")
+	headers := s.StringMap("headers", map[string]string{"a": "b"}, "Some headers")
+
+	err := s.ParseE(SourceStub{"labels": `{"env":"prod","team":"infra"}`})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "infra"}, *labels)
+	assert.Equal(t, map[string]string{"a": "b"}, *headers)
+}
+
+func TestParseEnum(t *testing.T) {
+	choices := []string{"a", "b", "c"}
+	assert.Panics(t, func() {
+		NewSet().Enum("enum", "d", choices, "Some enum")
+	})
+
+	s := NewSet()
+	e := s.Enum("enum", "a", choices, "Some enum")
+	eDef := s.Enum("enum-default", "a", choices, "Some enum")
+	err := s.ParseE(SourceStub{"enum": "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", *e)
+	assert.Equal(t, "a", *eDef)
+
+	s = NewSet()
+	s.RequiredEnum("enum", choices, "Some enum")
+	err = s.ParseE(SourceStub{"enum": "d"})
+	assert.EqualError(t, err, `parameter "enum" (from stub): "d" must be one of: a, b, c`)
+
+	s = NewSet()
+	e = s.Enum("enum", "a", choices, "Some enum", IgnoreCase())
+	s.Enum("enum-case", "a", choices, "Some enum")
+	err = s.ParseE(SourceStub{"enum": "B", "enum-case": "B"})
+	assert.EqualError(t, err, `parameter "enum-case" (from stub): "B" must be one of: a, b, c`)
+	assert.Equal(t, "b", *e)
+
+	assert.Panics(t, func() {
+		NewSet().String("str", "", "Not an enum", IgnoreCase())
+	})
+}
+
+func TestParseValidate(t *testing.T) {
+	assert.Panics(t, func() {
+		NewSet().String("str", "", "Some string", Min(1))
+	})
+	assert.Panics(t, func() {
+		NewSet().Int("int", 0, "Some int", Check(func(string) error { return nil }))
+	})
+
+	s := NewSet()
+	s.Int("int", 5, "Some int", Min(1), Max(10))
+	s.Uint("uint", 5, "Some uint", Min(-1), Max(1))
+	s.Duration("dur", time.Second, "Some duration", Min(time.Second))
+	s.String("str", "", "Some string", NonEmpty(), Match("^[a-z]+$"))
+	s.IntSlice("ints", nil, "Some ints", Check(func(ii []int) error {
+		if len(ii)%2 != 0 {
+			return errors.New("must be even in length")
+		}
+		return nil
+	}))
+	err := s.ParseE(SourceStub{
+		"int":  "11",
+		"dur":  "1ms",
+		"ints": `["1"]`,
+	})
+	assert.EqualError(t, err, `6 configuration errors:
+	parameter "dur" (from stub): must be at least 1s
+	parameter "int" (from stub): must be at most 10
+	parameter "ints" (from stub): must be even in length
+	parameter "str" (from default): must not be empty
+	parameter "str" (from default): "" does not match "^[a-z]+$"
+	parameter "uint" (from default): must be at most 1`)
+
+	s = NewSet()
+	i := s.Int("int", 5, "Some int", Min(1), Max(10))
+	str := s.String("str", "", "Some string", NonEmpty(), Match("^[a-z]+$"))
+	err = s.ParseE(SourceStub{"int": "10", "str": "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, *i)
+	assert.Equal(t, "foo", *str)
+}
+
+func TestShorthand(t *testing.T) {
+	s := NewSet()
+	s.Int("port", 80, "Some port", Shorthand('p'))
+	s.Int("port", 80, "Some port", Shorthand('p'))
+	assert.Panics(t, func() {
+		s.Bool("print", false, "Some bool", Shorthand('p'))
+	})
+	assert.Panics(t, func() {
+		s.Bool("hello", false, "Some bool", Shorthand('h'))
+	})
+}
+
+func TestArgs(t *testing.T) {
+	s := NewSet()
+	in := s.Arg("in", "Some input")
+	out := s.OptionalArg("out", "-", "Some output")
+	assert.Panics(t, func() {
+		s.Arg("other", "Some input")
+	})
+	s.Arg("in", "Some input")
+
+	err := s.ParseE(SourceStub{"in": "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", *in)
+	assert.Equal(t, "-", *out)
+
+	s = NewSet()
+	s.VariadicArgs("rest", "Some rest")
+	assert.Panics(t, func() {
+		s.OptionalArg("out", "", "Some output")
+	})
+}
+
+func TestSet(t *testing.T) {
+	s1, s2 := NewSet(), NewSet()
+	str1 := s1.String("str", "one", "Some string")
+	str2 := s2.String("str", "two", "Some string")
+	i2 := s2.Int("int", 5, "Some int")
+
+	var called bool
+	s1.Do(func() { called = true })
+
+	s2.Parse(SourceStub{"int": "8"})
+	assert.False(t, called)
+	assert.Equal(t, "two", *str2)
+	assert.Equal(t, 8, *i2)
+
+	s1.Parse(SourceStub{"int": "not used"})
+	assert.True(t, called)
+	assert.Equal(t, "one", *str1)
+}
+
 func TestDuplicateJSON(t *testing.T) {
 	var j jstruct
 	JSON(&j, "json", jstruct{Foo: "foo", Bar: 5}, "Some json")
@@ -107,6 +352,20 @@ func TestDuplicateJSON(t *testing.T) {
 	})
 }
 
+func TestRedefineOptions(t *testing.T) {
+	s := NewSet()
+	level := s.Enum("level", "info", []string{"info", "debug"}, "Level", Shorthand('l'))
+	// the options of a redefinition are ignored, even if they differ
+	assert.Equal(t, level, s.Enum("level", "info", []string{"info", "debug"}, "Level",
+		Shorthand('x'), Secret(), IgnoreCase()))
+	assert.Equal(t, 'l', s.m["level"].Short)
+	assert.False(t, s.m["level"].Secret)
+
+	assert.Panics(t, func() {
+		s.Enum("level", "debug", []string{"info", "debug"}, "Level")
+	})
+}
+
 func TestDo(t *testing.T) {
 	Reset()
 
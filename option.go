@@ -0,0 +1,40 @@
+package lflag
+
+// ParamOption modifies a param as it's being defined, e.g. to attach a
+// validator to it. ParamOptions may be passed into any of the functions which
+// define params, and are ignored if the param has already been defined, even if
+// they differ from those it was defined with.
+//
+// A ParamOption which doesn't make sense for the type of param it's given to
+// will panic when the param is defined.
+type ParamOption func(*param)
+
+// Shorthand is a ParamOption which gives the param a single character alias,
+// e.g. Shorthand('p') allows "-p 8080" to be used instead of "--port 8080" on
+// the command line. Defining two params with the same alias in a Set will
+// panic, as will using 'h' or 'V' which are reserved for help and version.
+func Shorthand(c rune) ParamOption {
+	return func(p *param) {
+		p.Short = c
+	}
+}
+
+// IgnoreCase is a ParamOption for params with choices, e.g. Enum, which allows
+// the value to match one of the choices regardless of case, e.g. "INFO" for
+// "info". The value is then set to the choice as it was given. Using it on a
+// param without choices will panic.
+func IgnoreCase() ParamOption {
+	return func(p *param) {
+		p.foldChoices = true
+	}
+}
+
+// Secret is a ParamOption which marks the param's value as sensitive, e.g. a
+// password or API key. Its value, and default, are then redacted in the --help
+// output, in errors returned from ParseE, and in the output of WriteEffective
+// and --print-config.
+func Secret() ParamOption {
+	return func(p *param) {
+		p.Secret = true
+	}
+}
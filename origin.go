@@ -0,0 +1,69 @@
+package lflag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParamOrigin describes where the value of a param came from, see Origin
+type ParamOrigin struct {
+	// Source is the name of the Source which supplied the value, e.g. "env" or
+	// "cli", or "default" if none did
+	Source string
+
+	// Key is what the Source supplied the value under, e.g. the environment
+	// variable DB_ADDR, the flag --db-addr, or the key db.addr in a config file
+	Key string
+
+	// File is the path of the file the value was read from, if any, e.g. a
+	// config file or one given by a DB_PASSWORD_FILE environment variable
+	File string
+
+	// Arg is the 1-based position amongst the command line arguments of the
+	// argument which supplied the value, or zero
+	Arg int
+}
+
+// String returns a description of the origin, e.g. "env DB_ADDR" or
+// "cli --db-addr, arg 3"
+func (o ParamOrigin) String() string {
+	var details []string
+	if o.Key != "" {
+		details = append(details, o.Key)
+	}
+	if o.File != "" {
+		details = append(details, "file "+o.File)
+	}
+	if o.Arg > 0 {
+		details = append(details, fmt.Sprintf("arg %d", o.Arg))
+	}
+	if len(details) == 0 {
+		return o.Source
+	}
+	return o.Source + " " + strings.Join(details, ", ")
+}
+
+// sourceOrigins returns the origins of the given values, all of which were
+// supplied by the named Source and found under the given keys, if any
+func sourceOrigins(name string, vals, keys map[string]string) map[string]ParamOrigin {
+	origins := make(map[string]ParamOrigin, len(vals))
+	for k := range vals {
+		origins[k] = ParamOrigin{Source: name, Key: keys[k]}
+	}
+	return origins
+}
+
+// Origin returns where the value of the named param, of the Set or any of the
+// subcommands selected by the last Parse, came from. The zero ParamOrigin is
+// returned if the param wasn't parsed. The origins of dynamic params (see
+// DefineDynamicIn) are updated by Reload.
+func (s *Set) Origin(name string) ParamOrigin {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.origins[name]
+}
+
+// Origin calls Origin on CommandLine
+func Origin(name string) ParamOrigin {
+	return CommandLine.Origin(name)
+}
@@ -0,0 +1,73 @@
+package lflag
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrigin(t *T) {
+	dir := t.TempDir()
+	dotEnv := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(dotEnv, []byte("LOG_LEVEL=debug\n"), 0600))
+	secret := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("hunter2\n"), 0600))
+	t.Setenv("DB_ADDR", "db:5432")
+	t.Setenv("DB_PASSWORD_FILE", secret)
+
+	s := NewSet()
+	s.String("db-addr", "", "Database address")
+	s.String("db-password", "", "Database password")
+	s.String("log-level", "info", "Log level")
+	s.String("name", "", "Name")
+	s.Int("port", 0, "Port", Shorthand('p'))
+	s.Bool("verbose", false, "Verbose", Shorthand('v'))
+	s.String("timeout", "1s", "Timeout")
+	s.Arg("target", "Target")
+
+	err := s.ParseE(Sources{
+		NewSourceDotEnv(dotEnv),
+		NewSourceEnv(EnvFiles()),
+		sourceCLI{testArgs: []string{"--name=foo", "-vp", "8080", "prod"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, ParamOrigin{Source: "dotenv", Key: "LOG_LEVEL", File: dotEnv}, s.Origin("log-level"))
+	assert.Equal(t, ParamOrigin{Source: "env", Key: "DB_ADDR"}, s.Origin("db-addr"))
+	assert.Equal(t, ParamOrigin{Source: "env", Key: "DB_PASSWORD_FILE", File: secret}, s.Origin("db-password"))
+	assert.Equal(t, ParamOrigin{Source: "cli", Key: "--name", Arg: 1}, s.Origin("name"))
+	assert.Equal(t, ParamOrigin{Source: "cli", Key: "-v", Arg: 2}, s.Origin("verbose"))
+	assert.Equal(t, ParamOrigin{Source: "cli", Key: "-p", Arg: 2}, s.Origin("port"))
+	assert.Equal(t, ParamOrigin{Source: "cli", Arg: 4}, s.Origin("target"))
+	assert.Equal(t, ParamOrigin{Source: "default"}, s.Origin("timeout"))
+	assert.Equal(t, ParamOrigin{}, s.Origin("unknown"))
+
+	assert.Equal(t, "env DB_PASSWORD_FILE, file "+secret, s.Origin("db-password").String())
+	assert.Equal(t, "cli -p, arg 2", s.Origin("port").String())
+	assert.Equal(t, "default", s.Origin("timeout").String())
+
+	var buf strings.Builder
+	require.NoError(t, s.WriteEffective(&buf, "table"))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 9)
+	assert.Equal(t, []string{"NAME", "VALUE", "ORIGIN"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"db-addr", "db:5432", "env", "DB_ADDR"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"timeout", "1s", "default"}, strings.Fields(lines[7]))
+}
+
+func TestOriginReload(t *T) {
+	s := NewSet()
+	level := DefineDynamicIn(s, "log-level", "info", "Log level", nil)
+	src := SourceStub{}
+	require.NoError(t, s.ParseE(src))
+	assert.Equal(t, ParamOrigin{Source: "default"}, s.Origin("log-level"))
+
+	src["log-level"] = "debug"
+	require.NoError(t, s.Reload())
+	assert.Equal(t, "debug", level.Get())
+	assert.Equal(t, ParamOrigin{Source: "stub"}, s.Origin("log-level"))
+}
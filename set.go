@@ -0,0 +1,448 @@
+package lflag
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Set is a collection of params along with the functions registered to be
+// called once they've been parsed using Do. Each Set is entirely independent of
+// every other, so that libraries, tests, and tools which manage multiple
+// configurations can each have their own.
+//
+// The package-level functions like String, Do, and Parse all operate on
+// CommandLine.
+type Set struct {
+	// HelpPrefix may be set as a prefix to be printed out by sources which can
+	// print out help strings (e.g. NewSourceCLI). If the string doesn't end in
+	// a newline one will be added
+	HelpPrefix string
+
+	m map[string]param
+	l sync.Mutex
+
+	// args are the positional arguments left over after Parse, see Args
+	args []string
+
+	// name, usage, run and parent are set on Sets returned from Command, and
+	// commands holds those defined on this Set
+	name, usage string
+	run         func()
+	parent      *Set
+	commands    map[string]*Set
+
+	// path holds the subcommands selected by the last Parse, see CommandPath
+	path []*Set
+
+	// src is the Source given to the last Parse, which is used when printing
+	// help and by Reload
+	src Source
+
+	// dynamic holds the params defined using DefineDynamicIn. parsed and
+	// parsedDynamic hold all the params, and the dynamic ones, which were
+	// given to the Source by the last Parse, for use by Reload
+	dynamic       map[string]*dynamicParam
+	parsed        []Param
+	parsedDynamic map[string]*dynamicParam
+
+	// files holds the config files read by the last Parse or Reload, see Watch
+	files []string
+
+	// values and origins hold the value of each param parsed by the last
+	// Parse, and where it came from, see Origin. Those of dynamic params are
+	// updated by Reload.
+	values  map[string]string
+	origins map[string]ParamOrigin
+
+	// printConfig is the format the effective configuration should be printed
+	// out in once parsed, if --print-config was given, see WriteEffective
+	printConfig string
+
+	// queueCh is used to queue up future Do's
+	queueCh chan func()
+
+	// doneCh is closed once spin is done and all queued up Do's have been
+	// called this is used to signal to future Do's that they shouldn't queue
+	doneCh chan bool
+
+	// callCh is used by Parse to get all queued up Do's
+	callCh chan func()
+
+	// spinWg is only needed to keep track of spin for Reset to know when its
+	// safe to reset
+	spinWg sync.WaitGroup
+}
+
+// CommandLine is the default Set, used by all of the package-level functions.
+// It has the log-level param defined on it.
+var CommandLine = NewSet()
+
+// NewSet initializes and returns a new, empty Set
+func NewSet() *Set {
+	s := new(Set)
+	s.init()
+	return s
+}
+
+func (s *Set) init() {
+	s.m = map[string]param{}
+	s.args = nil
+	s.commands = nil
+	s.path = nil
+	s.src = nil
+	s.dynamic = nil
+	s.parsed = nil
+	s.parsedDynamic = nil
+	s.files = nil
+	s.values = nil
+	s.origins = nil
+	s.printConfig = ""
+	s.queueCh = make(chan func())
+	s.doneCh = make(chan bool)
+	s.callCh = make(chan func())
+
+	s.spinWg.Add(1)
+	go s.spin()
+}
+
+func (s *Set) spin() {
+	defer s.spinWg.Done()
+	dos := []func(){
+		// seed this with an empty one so the for loop doesn't immediately break
+		func() {},
+	}
+loop:
+	for len(dos) > 0 {
+		select {
+		case fn, ok := <-s.queueCh:
+			// only Reset closes queueCh and so we should bail immediately
+			if !ok {
+				break loop
+			}
+			dos = append(dos, fn)
+		case s.callCh <- dos[0]:
+			dos = dos[1:]
+		}
+	}
+	// trigger all waiting/future Do calls to immediately happen
+	close(s.doneCh)
+	// tell Parse to stop looping
+	close(s.callCh)
+}
+
+// helpPrefix returns the HelpPrefix which should be used for this Set. The
+// package-level HelpPrefix is used by CommandLine if its own isn't set.
+func (s *Set) helpPrefix() string {
+	if s.HelpPrefix == "" && s == CommandLine {
+		return HelpPrefix
+	}
+	return s.HelpPrefix
+}
+
+type param struct {
+	Param
+	ptr        interface{}
+	validators []validator
+
+	// parse, if set, is used instead of the ParseFunc of the ParamType
+	parse ParseFunc
+
+	// foldChoices is set by IgnoreCase
+	foldChoices bool
+
+	// defined is the Param as it was defined, before any ParamOptions were
+	// applied, which a redefinition of the param is compared against
+	defined Param
+}
+
+// choice returns the choice which val matches regardless of case, if the param
+// was defined with IgnoreCase, or val as-is otherwise
+func (p param) choice(val string) string {
+	if p.foldChoices {
+		for _, c := range p.Choices {
+			if strings.EqualFold(val, c) {
+				return c
+			}
+		}
+	}
+	return val
+}
+
+func (p param) parseFunc() ParseFunc {
+	if p.parse != nil {
+		return p.parse
+	}
+	return paramTypeParsers[p.ParamType]
+}
+
+func (s *Set) newParam(p Param, ptr interface{}, opts ...ParamOption) interface{} {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	np := param{Param: p, ptr: ptr}
+	if np.Positional == positionalNext {
+		np.Positional = s.nextPositional(np.Param)
+	}
+
+	// the options of a redefinition are ignored, see ParamOption
+	if pp, ok := s.m[p.Name]; ok && !reflect.DeepEqual(pp.defined, np.Param) {
+		panic(fmt.Sprintf("param named %q already exists and differs from this new one", p.Name))
+	} else if ok {
+		return pp.ptr
+	}
+
+	np.defined = np.Param
+	for _, opt := range opts {
+		opt(&np)
+	}
+
+	if np.foldChoices && len(np.Choices) == 0 {
+		panic(fmt.Sprintf("param %q uses IgnoreCase but has no choices", np.Name))
+	}
+
+	if np.Short != 0 {
+		if np.Short == 'h' || np.Short == 'V' {
+			panic(fmt.Sprintf("param %q can't use reserved short alias -%c", np.Name, np.Short))
+		}
+		for _, pp := range s.m {
+			if pp.Short == np.Short {
+				panic(fmt.Sprintf("param %q has the same short alias -%c as %q", np.Name, np.Short, pp.Name))
+			}
+		}
+	}
+
+	s.m[p.Name] = np
+	return np.ptr
+}
+
+// positionalNext is used as the Positional of a Param given to newParam to
+// indicate it should be the next positional param
+const positionalNext = -1
+
+// nextPositional returns the Positional which the given Param should have if
+// it's defined as the next positional param, and panics if it can't be.
+func (s *Set) nextPositional(p Param) int {
+	if pp, ok := s.m[p.Name]; ok && pp.Positional > 0 {
+		// the param is being redefined, newParam will check it's the same
+		return pp.Positional
+	}
+
+	var last Param
+	for _, pp := range s.m {
+		if pp.Positional > last.Positional {
+			last = pp.Param
+		}
+	}
+	if _, ok := listParamTypes[last.ParamType]; ok && last.Positional > 0 {
+		panic(fmt.Sprintf("positional param %q can't be defined after variadic %q", p.Name, last.Name))
+	} else if p.Required && last.Positional > 0 && !last.Required {
+		panic(fmt.Sprintf("required positional param %q can't be defined after optional %q", p.Name, last.Name))
+	}
+	return last.Positional + 1
+}
+
+// Do registers the given function to be performed after Parse is called an all
+// param pointers have been filled in. Multiple functions may be registered
+// using Do, though the order they are called is guaranteed to be in calling
+// order. Once Parse has been called and ALL queued Do functions have returned,
+// sent function is immediately invoked. If Do is called within another Do, then
+// the sent function is added to the queue at the end and will be envoked once all
+// queued up Do's are called.
+func (s *Set) Do(fn func()) {
+	select {
+	// if doneCh is closed then we immediately call, otherwise we add it to the queue
+	case <-s.doneCh:
+		fn()
+	case s.queueCh <- fn:
+	}
+}
+
+// Reset removes any configured flags and resets the Set back to before Parse
+// was called.
+func (s *Set) Reset() {
+	// closing queueCh will immediately kill spin
+	close(s.queueCh)
+	s.spinWg.Wait()
+	s.init()
+}
+
+// Parse goes through each Source and compiles a set of values for registered
+// params (later Sources overwrite previous ones), fills in param pointers, then
+// calls all functions registered using Do.
+//
+// At the end of Parse all of the Set's params are reset. Any calls to Do will
+// immediately invoke the sent function after Parse is called.
+//
+// If ParseE would have returned an error then it is printed to stderr and the
+// process exits with a non-zero status.
+func (s *Set) Parse(src Source) {
+	if err := s.ParseE(src); err != nil {
+		errorAndExit(err)
+	}
+}
+
+// ParseE is like Parse, but rather than exiting it returns an Errors containing
+// every problem encountered: errors returned by Sources, Required params which
+// weren't set, values which couldn't be parsed, and values which failed the
+// validation given by their ParamOptions. If any are encountered then
+// none of the functions registered using Do are called.
+func (s *Set) ParseE(src Source) error {
+	run, err := s.parse(src)
+	if err != nil {
+		return err
+	}
+
+	if s.printConfig != "" {
+		buf := new(bytes.Buffer)
+		if err := s.WriteEffective(buf, s.printConfig); err != nil {
+			return err
+		}
+		printfAndExit("%s", buf.String())
+	}
+
+	// the Set isn't held while calling the Do functions, so that they may
+	// make use of it (e.g. by calling Args)
+	for _, set := range append([]*Set{s}, s.path...) {
+		for fn := range set.callCh {
+			fn()
+		}
+	}
+	if run != nil {
+		run()
+	}
+	return nil
+}
+
+// parse fills in the pointers of the Set's params, and of those of any selected
+// subcommands, and returns the run function of the last selected subcommand.
+func (s *Set) parse(src Source) (func(), error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.src = src
+	s.path = s.resolvePath(src)
+	for _, c := range s.path {
+		c.l.Lock()
+		defer c.l.Unlock()
+	}
+
+	m, errs := s.pathParams(s.path)
+	pp := make([]Param, 0, len(m))
+	for _, p := range m {
+		pp = append(pp, p.Param)
+	}
+	sort.Slice(pp, func(i, j int) bool {
+		return pp[i].Name < pp[j].Name
+	})
+
+	st := &parseState{set: s}
+	vals, origins, err := st.parseSource(src, pp)
+	if err != nil {
+		errs = errs.append(err)
+	}
+	s.args, s.printConfig, s.files = st.args, st.printConfig, st.files
+
+	values := make(map[string]string, len(pp))
+	valOrigins := make(map[string]ParamOrigin, len(pp))
+
+	for _, p := range pp {
+		pr := m[p.Name]
+		val, valOk := vals[p.Name]
+		origin := origins[p.Name]
+		if !valOk {
+			if p.Required {
+				errs = append(errs, &Error{Param: p.Name, Err: ErrRequired})
+				continue
+			}
+			val, origin = p.Default, ParamOrigin{Source: "default"}
+		}
+		val = pr.choice(val)
+		values[p.Name], valOrigins[p.Name] = val, origin
+
+		if err := checkChoices(p, val); err != nil {
+			errs = append(errs, &Error{Param: p.Name, Source: origin.Source, Err: redactError(p, err)})
+			continue
+		}
+
+		// params with their own ParseFunc have their pointer initialized to
+		// the default when defined, see DefineIn and Var
+		if valOk || pr.parse == nil {
+			err := pr.parseFunc()(val, pr.ptr)
+			if err != nil {
+				errs = append(errs, &Error{Param: p.Name, Source: origin.Source, Err: redactError(p, err)})
+				continue
+			}
+		}
+
+		for _, v := range pr.validators {
+			if err := v(pr.ptr); err != nil {
+				errs = append(errs, &Error{Param: p.Name, Source: origin.Source, Err: redactError(p, err)})
+			}
+		}
+	}
+
+	last := s
+	if len(s.path) > 0 {
+		last = s.path[len(s.path)-1]
+	}
+	if len(last.commands) > 0 && last.run == nil {
+		errs = append(errs, &Error{
+			Err: fmt.Errorf("%w, one of: %s", ErrCommandRequired, strings.Join(last.commandNames(), ", ")),
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	s.parsed = pp
+	s.values, s.origins = values, valOrigins
+	s.parsedDynamic = map[string]*dynamicParam{}
+	for _, set := range append([]*Set{s}, s.path...) {
+		for name, dp := range set.dynamic {
+			dp.param = m[name]
+			dp.value.store(dp.ptr)
+			s.parsedDynamic[name] = dp
+		}
+	}
+
+	s.m = map[string]param{}
+	for _, c := range s.path {
+		c.m = map[string]param{}
+		c.args = s.args
+	}
+	return last.run, nil
+}
+
+func checkChoices(p Param, val string) error {
+	if len(p.Choices) == 0 {
+		return nil
+	}
+	for _, c := range p.Choices {
+		if val == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q must be one of: %s", val, strings.Join(p.Choices, ", "))
+}
+
+// Configure is a shortcut around Parse which uses our default sources (in order
+// of least-to-most precedent: json-file, .env file in the working directory,
+// environment, cli).
+func (s *Set) Configure() {
+	if err := s.ConfigureE(); err != nil {
+		errorAndExit(err)
+	}
+}
+
+// ConfigureE is like Configure, but returns any error from ParseE rather than
+// exiting.
+func (s *Set) ConfigureE() error {
+	var src Source = Sources{NewSourceDotEnv(".env"), NewSourceEnv(), NewSourceCLI()}
+	src = NewSourceJSON(src)
+	return s.ParseE(src)
+}
@@ -1,5 +1,7 @@
 package lflag
 
+import "fmt"
+
 // Param describes everything a Source needs to know about a single
 // configuration option which has been defined
 type Param struct {
@@ -21,6 +23,29 @@ type Param struct {
 
 	// Required should be true if the parameter must be provided by the caller
 	Required bool
+
+	// Choices, if not empty, is the set of values which the parameter may
+	// take. Any other value will cause an error during Parse.
+	Choices []string
+
+	// Positional, if greater than zero, is the 1-based position of the
+	// parameter amongst the positional command line arguments, see Arg.
+	// Positional parameters are only set by NewSourceCLI.
+	Positional int
+
+	// Short is an optional single character alias for the parameter, e.g. 'p'
+	// for "port", for use by Sources like NewSourceCLI. Zero means no alias.
+	Short rune
+
+	// Secret should be true if the parameter's value is sensitive, e.g. a
+	// password, in which case it's redacted wherever it would be shown, see
+	// Secret.
+	Secret bool
+
+	// paths is true for the params giving the names of config files, see
+	// configFile. Their values are lists, but Sources shouldn't split them on a
+	// separator, since a path may contain one.
+	paths bool
 }
 
 // Source describes an entity which actually provides the values for
@@ -32,9 +57,101 @@ type Source interface {
 	//
 	// NOTE the only valid true value for a ParamTypeBool is "true". All other
 	// values will be considered false
+	//
+	// A Source may wrap the built-in Sources, but their Parse methods don't know
+	// about the Set being parsed. So within a custom Source the leftover command
+	// line arguments aren't available from Args, --print-config does nothing,
+	// --help doesn't show the HelpPrefix or subcommands, and config files aren't
+	// watched by Watch.
 	Parse([]Param) (map[string]string, error)
 }
 
+// setSource is implemented by Sources which need to know about the Set being
+// parsed (e.g. for its HelpPrefix), which wrap other Sources, or which can say
+// more about where each value came from than their name, so that the origin of
+// each value can be tracked, see Origin.
+type setSource interface {
+	parseSet(*parseState, []Param) (vals map[string]string, origins map[string]ParamOrigin, err error)
+}
+
+// parseState is passed to each setSource during a parse. Sources may read the
+// Set being parsed from it, but mustn't modify the Set. Anything they find
+// other than values is recorded in the parseState instead, and is only stored
+// in the Set once the parse is done.
+type parseState struct {
+	set *Set
+
+	args        []string // the positional arguments left over, see Args
+	printConfig string   // the format given to --print-config, if any
+	files       []string // the config files which were read, see Watch
+}
+
+// detachedState returns the parseState used when Parse is called on a built-in
+// Source directly, e.g. by a custom Source wrapping it. There's no Set being
+// parsed then, so an empty one is used and whatever is recorded is dropped.
+func detachedState() *parseState {
+	return &parseState{set: new(Set)}
+}
+
+// wrapperSource is implemented by Sources which wrap other Sources
+type wrapperSource interface {
+	innerSources() []Source
+}
+
+// findSource returns the first Source of type T which is, or is wrapped by, the
+// given one
+func findSource[T Source](src Source) (T, bool) {
+	if s, ok := src.(T); ok {
+		return s, true
+	} else if ws, ok := src.(wrapperSource); ok {
+		for _, inner := range ws.innerSources() {
+			if s, ok := findSource[T](inner); ok {
+				return s, true
+			}
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// sourceName returns the name used to describe the given Source in errors. If
+// the Source implements fmt.Stringer then that is used.
+func sourceName(s Source) string {
+	if str, ok := s.(fmt.Stringer); ok {
+		return str.String()
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+// sourceErrors converts an error returned from a Source into an Errors, filling
+// in the Source field of any which don't already have it set
+func sourceErrors(s Source, err error) Errors {
+	ee := Errors(nil).append(err)
+	for _, e := range ee {
+		if e.Source == "" {
+			e.Source = sourceName(s)
+		}
+	}
+	return ee
+}
+
+// parseSource calls Parse on the given Source, returning the values along with
+// the origin of each one. Unlike Parse the returned
+// values may be non-nil even if an error is returned, so that all errors can be
+// gathered in one pass.
+func (st *parseState) parseSource(s Source, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	if ss, ok := s.(setSource); ok {
+		return ss.parseSet(st, pp)
+	}
+
+	vals, err := s.Parse(pp)
+	if err != nil {
+		return nil, nil, sourceErrors(s, err)
+	}
+
+	return vals, sourceOrigins(sourceName(s), vals, nil), nil
+}
+
 // SourceStub can be used for testing with configuration options
 type SourceStub map[string]string
 
@@ -43,22 +160,46 @@ func (ss SourceStub) Parse([]Param) (map[string]string, error) {
 	return ss, nil
 }
 
+func (ss SourceStub) String() string {
+	return "stub"
+}
+
 // Sources encompasses multiple Source instances. When Parse is called the
 // returned map from each will be combined together, with right-most Source
 // values taking precedence over their lefthand neighbor
 type Sources []Source
 
-// Parse implements the Source interface. See Sources' doc
+// Parse implements the Source interface. See Sources' doc. If any of the
+// Sources return an error then the returned error will be an Errors containing
+// all of them.
 func (ss Sources) Parse(pp []Param) (map[string]string, error) {
+	vals, _, err := ss.parseSet(detachedState(), pp)
+	if err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+func (ss Sources) innerSources() []Source {
+	return ss
+}
+
+func (ss Sources) parseSet(st *parseState, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	var errs Errors
 	vals := map[string]string{}
+	origins := map[string]ParamOrigin{}
 	for _, s := range ss {
-		sm, err := s.Parse(pp)
+		sm, so, err := st.parseSource(s, pp)
 		if err != nil {
-			return nil, err
+			errs = errs.append(err)
 		}
 		for k, v := range sm {
 			vals[k] = v
+			origins[k] = so[k]
 		}
 	}
-	return vals, nil
+	if len(errs) > 0 {
+		return vals, origins, errs
+	}
+	return vals, origins, nil
 }
@@ -0,0 +1,213 @@
+package lflag
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// structFieldParamTypes maps the types of struct fields which Struct supports
+// to their ParamTypes. Fields whose pointer implements encoding.TextUnmarshaler
+// are also supported.
+var structFieldParamTypes = map[reflect.Type]string{
+	reflect.TypeOf(""):                     ParamTypeString,
+	reflect.TypeOf(int(0)):                 ParamTypeInt,
+	reflect.TypeOf(int64(0)):               ParamTypeInt64,
+	reflect.TypeOf(uint(0)):                ParamTypeUint,
+	reflect.TypeOf(uint64(0)):              ParamTypeUint64,
+	reflect.TypeOf(float64(0)):             ParamTypeFloat64,
+	reflect.TypeOf(false):                  ParamTypeBool,
+	durationType:                           ParamTypeDuration,
+	reflect.TypeOf([]string(nil)):          ParamTypeStringSlice,
+	reflect.TypeOf([]int(nil)):             ParamTypeIntSlice,
+	reflect.TypeOf([]time.Duration(nil)):   ParamTypeDurationSlice,
+	reflect.TypeOf(map[string]string(nil)): ParamTypeStringMap,
+}
+
+// Struct defines a param for each tagged field of the struct which ptr points
+// to. The fields are filled in when Parse is called, like the pointers returned
+// from String, Int, etc.
+//
+// The following struct tags are used:
+//
+//	lflag:"name"     The name of the param, required for a field to be used.
+//	default:"value"  The default value, in the same form a Source would give.
+//	usage:"text"     The usage string of the param.
+//	required:"true"  Makes the param Required.
+//	short:"p"        Gives the param a short alias, see Shorthand.
+//
+// If the default tag isn't given then the current value of the field is used
+// as the default. The default of a list or map field may be given like the
+// value of an environment variable (see NewSourceEnv), e.g. default:"a,b" or
+// default:"env=prod,team=infra".
+//
+// A field which is itself a struct (and doesn't implement
+// encoding.TextUnmarshaler) has its own fields walked, with their names
+// prefixed by its lflag tag (using Prefixed) if it has one. All param names are
+// prefixed by the given prefix, which may be empty.
+//
+//	type DBConfig struct {
+//		Addr     string `lflag:"addr" default:":666" usage:"Address of the database"`
+//		PoolSize int    `lflag:"pool-size" default:"10" usage:"Number of connections"`
+//	}
+//
+//	var cfg struct {
+//		DB DBConfig `lflag:"db"` // defines db-addr and db-pool-size
+//	}
+//	lflag.Struct(&cfg, "")
+//
+// Struct panics if ptr isn't a pointer to a struct, or if a tagged field has an
+// unsupported type or an invalid default.
+func (s *Set) Struct(ptr interface{}, prefix string) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("lflag: Struct expects a pointer to a struct, got %T", ptr))
+	}
+	s.structFields(v.Elem(), prefix)
+}
+
+// Struct calls Struct on CommandLine
+func Struct(ptr interface{}, prefix string) {
+	CommandLine.Struct(ptr, prefix)
+}
+
+func (s *Set) structFields(v reflect.Value, prefix string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		name, tagged := f.Tag.Lookup("lflag")
+		if f.PkgPath != "" || name == "-" {
+			continue
+		}
+
+		isText := reflect.PtrTo(f.Type).Implements(textUnmarshalerType)
+		if f.Type.Kind() == reflect.Struct && !isText {
+			s.structFields(fv, Prefixed(prefix, name))
+			continue
+		} else if !tagged {
+			continue
+		}
+
+		s.structField(f, fv, Prefixed(prefix, name), isText)
+	}
+}
+
+func (s *Set) structField(f reflect.StructField, fv reflect.Value, name string, isText bool) {
+	p := Param{
+		Name:  name,
+		Usage: f.Tag.Get("usage"),
+	}
+	if req := f.Tag.Get("required"); req != "" {
+		var err error
+		if p.Required, err = strconv.ParseBool(req); err != nil {
+			panic(fmt.Sprintf("lflag: field %s has invalid required tag: %v", f.Name, err))
+		}
+	}
+
+	def, hasDef := f.Tag.Lookup("default")
+	ptr := fv.Addr().Interface()
+	var opts []ParamOption
+	if short := []rune(f.Tag.Get("short")); len(short) == 1 {
+		opts = append(opts, Shorthand(short[0]))
+	} else if len(short) > 1 {
+		panic(fmt.Sprintf("lflag: field %s has a short tag longer than one character", f.Name))
+	}
+	if paramType, ok := structFieldParamTypes[f.Type]; ok {
+		p.ParamType = paramType
+		if !hasDef {
+			def = fieldDefault(fv)
+		} else {
+			var err error
+			if def, err = structDefault(paramType, def); err == nil {
+				err = paramTypeParsers[paramType](def, reflect.New(f.Type).Interface())
+			}
+			if err != nil {
+				panic(fmt.Sprintf("lflag: field %s has invalid default: %v", f.Name, err))
+			}
+		}
+	} else if isText {
+		p.ParamType = valueParamType(ptr)
+		opts = append(opts, withParseFunc(parseTextUnmarshaler))
+		// the pointer isn't parsed unless a Source sets the param, so the
+		// default needs to be filled in now
+		if !hasDef {
+			def = defaultString(fv.Interface())
+		} else if err := parseTextUnmarshaler(def, ptr); err != nil {
+			panic(fmt.Sprintf("lflag: field %s has invalid default: %v", f.Name, err))
+		}
+	} else {
+		panic(fmt.Sprintf("lflag: field %s has unsupported type %s", f.Name, f.Type))
+	}
+
+	if !p.Required {
+		p.Default = def
+	}
+
+	if got := s.newParam(p, ptr, opts...); got != ptr {
+		panic(fmt.Sprintf("param named %q already exists and differs from this new one", p.Name))
+	}
+}
+
+// structDefault converts the default tag of a field whose type is in
+// structFieldParamTypes into the param's string form. Lists and maps may be
+// given like the value of an environment variable, or already in that form.
+func structDefault(paramType, def string) (string, error) {
+	se := sourceEnv{separator: ","}
+	if _, ok := listParamTypes[paramType]; ok {
+		if _, err := splitList(def); err != nil {
+			return se.splitList(def), nil
+		}
+	} else if paramType == ParamTypeStringMap {
+		if err := parseParamTypeStringMap(def, new(map[string]string)); err != nil {
+			return se.splitMap(def)
+		}
+	}
+	return def, nil
+}
+
+// fieldDefault returns the string form of the current value of a struct field
+// whose type is in structFieldParamTypes, in the same form as the Default
+// which the equivalent function (e.g. Int) would use
+func fieldDefault(fv reflect.Value) string {
+	switch fv.Interface().(type) {
+	case string:
+		return fv.String()
+	case bool:
+		if fv.Bool() {
+			return "true"
+		}
+		return ""
+	case time.Duration:
+		return time.Duration(fv.Int()).String()
+	case int, int64:
+		return strconv.FormatInt(fv.Int(), 10)
+	case uint, uint64:
+		return strconv.FormatUint(fv.Uint(), 10)
+	case float64:
+		return strconv.FormatFloat(fv.Float(), 'g', -1, 64)
+	case []string:
+		return joinList(fv.Interface().([]string))
+	case []int:
+		strs := make([]string, fv.Len())
+		for i, v := range fv.Interface().([]int) {
+			strs[i] = strconv.Itoa(v)
+		}
+		return joinList(strs)
+	case []time.Duration:
+		strs := make([]string, fv.Len())
+		for i, v := range fv.Interface().([]time.Duration) {
+			strs[i] = v.String()
+		}
+		return joinList(strs)
+	case map[string]string:
+		return joinMap(fv.Interface().(map[string]string))
+	}
+	panic(fmt.Sprintf("lflag: unsupported type %s", fv.Type()))
+}
@@ -0,0 +1,95 @@
+package lflag
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDBConfig struct {
+	Addr     string        `lflag:"addr" default:":666" usage:"Address of the database"`
+	PoolSize int           `lflag:"pool-size" default:"10"`
+	Timeout  time.Duration `lflag:"timeout"`
+	Password string        `lflag:"password" required:"true"`
+}
+
+type testConfig struct {
+	DB      testDBConfig `lflag:"db"`
+	Peers   []string     `lflag:"peers"`
+	Labels  map[string]string
+	IP      net.IP `lflag:"ip" default:"127.0.0.1"`
+	Verbose bool   `lflag:"verbose"`
+	Skipped string `lflag:"-"`
+
+	unexported string `lflag:"unexported"`
+}
+
+func TestStruct(t *testing.T) {
+	s := NewSet()
+	cfg := testConfig{
+		DB:    testDBConfig{Timeout: time.Second},
+		Peers: []string{"a"},
+	}
+	s.Struct(&cfg, "app")
+
+	err := s.ParseE(SourceStub{
+		"app-db-addr":     "db:666",
+		"app-db-password": "hunter2",
+		"app-peers":       `["b","c"]`,
+		"app-verbose":     "true",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, testConfig{
+		DB: testDBConfig{
+			Addr:     "db:666",
+			PoolSize: 10,
+			Timeout:  time.Second,
+			Password: "hunter2",
+		},
+		Peers:   []string{"b", "c"},
+		IP:      net.IPv4(127, 0, 0, 1),
+		Verbose: true,
+	}, cfg)
+
+	s = NewSet()
+	s.Struct(&cfg, "")
+	err = s.ParseE(SourceStub{})
+	assert.EqualError(t, err, `parameter "db-password": required but not set`)
+
+	// list and map defaults may be given like env values, or as-is
+	var defaults struct {
+		Tags   []string          `lflag:"tags" default:"a, b"`
+		IDs    []int             `lflag:"ids" default:"[\"1\",\"2\"]"`
+		Labels map[string]string `lflag:"labels" default:"env=prod,team=infra"`
+	}
+	s = NewSet()
+	s.Struct(&defaults, "")
+	assert.NoError(t, s.ParseE(SourceStub{}))
+	assert.Equal(t, []string{"a", "b"}, defaults.Tags)
+	assert.Equal(t, []int{1, 2}, defaults.IDs)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "infra"}, defaults.Labels)
+
+	assert.PanicsWithValue(t, `lflag: field IDs has invalid default: "x" is not a valid int`, func() {
+		var bad struct {
+			IDs []int `lflag:"ids" default:"1,x"`
+		}
+		NewSet().Struct(&bad, "")
+	})
+	assert.Panics(t, func() {
+		var bad struct {
+			Labels map[string]string `lflag:"labels" default:"env"`
+		}
+		NewSet().Struct(&bad, "")
+	})
+	assert.Panics(t, func() {
+		NewSet().Struct(cfg, "")
+	})
+	assert.Panics(t, func() {
+		var bad struct {
+			C chan int `lflag:"c"`
+		}
+		NewSet().Struct(&bad, "")
+	})
+}
@@ -0,0 +1,159 @@
+package lflag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+type sourceTOML struct {
+	innerSrc     Source
+	testTOMLFile io.Reader // used as a fake toml file in tests
+}
+
+// NewSourceTOML wraps an existing Source and adds support for reading a toml
+// file to source parameter values. The values coming from the inner Source will
+// overwrite any which are found in the toml file.
+//
+// The file is named by the config-toml-file param, which may be given multiple
+// times, e.g. a base file followed by an environment's overlay, with values in
+// later files overwriting earlier ones. It may also name a directory, in which
+// case the *.toml files within it are read in lexical order.
+//
+// The file may include others by giving their paths, relative to its own
+// directory, under the "$include" key, e.g. "$include" = ["base.toml"]. Those
+// are read before the file itself, so that it overwrites them.
+//
+// The keys within a table are prefixed by the table's name, like Prefixed, so
+// "addr" within the table "db" is the value of the "db-addr" param. List params
+// (e.g. StringSlice) are given as toml arrays in the file, and map params (e.g.
+// StringMap) as toml tables.
+func NewSourceTOML(inner Source) Source {
+	return sourceTOML{innerSrc: inner}
+}
+
+func (st sourceTOML) Parse(pp []Param) (map[string]string, error) {
+	vals, _, err := st.parseSet(detachedState(), pp)
+	if err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+func (st sourceTOML) String() string {
+	return "toml"
+}
+
+func (st sourceTOML) innerSources() []Source {
+	return []Source{st.innerSrc}
+}
+
+func (st sourceTOML) parseSet(state *parseState, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	return configFile{
+		src:      st,
+		inner:    st.innerSrc,
+		name:     "config-toml-file",
+		kind:     "toml",
+		exts:     []string{".toml"},
+		testFile: st.testTOMLFile,
+		parse:    st.parseFile,
+	}.parseSet(state, pp)
+}
+
+// parseFile decodes the given toml file and returns the values found in it for
+// the given params
+func (st sourceTOML) parseFile(r io.Reader, pp []Param) (map[string]string, map[string]string, []string, error) {
+	var tree map[string]interface{}
+	if _, err := toml.NewDecoder(r).Decode(&tree); err != nil {
+		return nil, nil, nil, err
+	}
+
+	includes, err := configIncludes(tree[includeKey])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	delete(tree, includeKey)
+
+	vals, keys, err := flattenConfig(tree, pp, tomlString, configTable)
+	return vals, keys, includes, err
+}
+
+// tomlString converts a value decoded from a toml file into the string form for
+// the given param
+func tomlString(p Param, v interface{}) (string, error) {
+	if _, ok := listParamTypes[p.ParamType]; ok {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return "", errors.New("expected an array")
+		}
+		strs := make([]string, len(arr))
+		for i, elem := range arr {
+			var err error
+			if strs[i], err = tomlScalarString(elem); err != nil {
+				return "", err
+			}
+		}
+		return joinListKeepEmpty(strs), nil
+	}
+
+	switch p.ParamType {
+	case ParamTypeStringMap:
+		tm, ok := v.(map[string]interface{})
+		if !ok {
+			return "", errors.New("expected a table")
+		}
+		m := make(map[string]string, len(tm))
+		for k, elem := range tm {
+			var err error
+			if m[k], err = tomlScalarString(elem); err != nil {
+				return "", err
+			}
+		}
+		return joinMapKeepEmpty(m), nil
+
+	case ParamTypeJSON:
+		return tomlJSONString(v)
+	}
+
+	str, err := tomlScalarString(v)
+	if _, custom := customParamTypeTypes[p.ParamType]; err != nil && (custom || paramTypeParsers[p.ParamType] == nil) {
+		// params without a built-in ParamType, e.g. those defined using
+		// Define, are given the json form of arrays and tables
+		return tomlJSONString(v)
+	}
+	return str, err
+}
+
+// tomlScalarString returns the string form of a single toml value, e.g. a
+// string, number, boolean or datetime
+func tomlScalarString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		// toml.LocalDate and the like
+		return v.String(), nil
+	}
+	return "", errors.New("expected a single value")
+}
+
+func tomlJSONString(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
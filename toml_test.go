@@ -0,0 +1,90 @@
+package lflag
+
+import (
+	"bytes"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceTOML(t *T) {
+	pp := []Param{
+		{ParamType: ParamTypeString, Name: "str"},
+		{ParamType: ParamTypeString, Name: "str2"},
+		{ParamType: ParamTypeInt, Name: "int"},
+		{ParamType: ParamTypeFloat64, Name: "float64"},
+		{ParamType: ParamTypeBool, Name: "bool"},
+		{ParamType: ParamTypeDuration, Name: "dur"},
+		{ParamType: ParamTypeString, Name: "when"},
+		{ParamType: ParamTypeJSON, Name: "json"},
+		{ParamType: ParamTypeStringSlice, Name: "strs"},
+		{ParamType: ParamTypeIntSlice, Name: "ints"},
+		{ParamType: ParamTypeStringMap, Name: "labels"},
+		{ParamType: ParamTypeString, Name: "db-addr"},
+		{ParamType: ParamTypeInt, Name: "db-replica-port"},
+		{ParamType: ParamTypeStringMap, Name: "db-opts"},
+	}
+
+	ts := SourceStub{
+		"str2": "bar", // this should overwrite the one in the tomlFile
+	}
+
+	tomlFile := bytes.NewBufferString(`
+str = "foo\nsomething"
+str2 = "broken"
+int = 1
+float64 = 1.5
+bool = true
+dur = "30s"
+when = 2006-01-02T15:04:05Z
+json = {foo = "bar"}
+strs = ["a", "b"]
+ints = []
+labels = {env = "prod", shard = 1}
+
+[db]
+addr = "localhost:5432"
+opts = {}
+
+[db.replica]
+port = 5433
+`)
+
+	m, err := sourceTOML{innerSrc: ts, testTOMLFile: tomlFile}.Parse(pp)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"str":             "foo\nsomething",
+		"str2":            "bar",
+		"int":             "1",
+		"float64":         "1.5",
+		"bool":            "true",
+		"dur":             "30s",
+		"when":            "2006-01-02T15:04:05Z",
+		"json":            `{"foo":"bar"}`,
+		"strs":            `["a","b"]`,
+		"ints":            "[]",
+		"labels":          `{"env":"prod","shard":"1"}`,
+		"db-addr":         "localhost:5432",
+		"db-replica-port": "5433",
+		"db-opts":         "{}",
+	}, m)
+
+	_, err = sourceTOML{innerSrc: SourceStub{}, testTOMLFile: bytes.NewBufferString(`
+str = ["a"]
+strs = "a"
+`)}.Parse(pp)
+	assert.EqualError(t, err, `2 configuration errors:
+	parameter "str" (from toml): expected a single value
+	parameter "strs" (from toml): expected an array`)
+}
+
+func TestSourceTOMLConflict(t *T) {
+	pp := []Param{{ParamType: ParamTypeString, Name: "db-addr"}}
+	_, err := sourceTOML{innerSrc: SourceStub{}, testTOMLFile: bytes.NewBufferString(`
+db-addr = "flat"
+
+[db]
+addr = "nested"
+`)}.Parse(pp)
+	assert.EqualError(t, err, `parameter "db-addr" (from toml): given by both "db.addr" and "db-addr"`)
+}
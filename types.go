@@ -2,8 +2,11 @@ package lflag
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,11 +15,31 @@ const (
 	ParamTypeString   = "string"
 	ParamTypeInt      = "int"
 	ParamTypeInt64    = "int64"
+	ParamTypeUint     = "uint"
+	ParamTypeUint64   = "uint64"
+	ParamTypeFloat64  = "float64"
 	ParamTypeBool     = "bool"
 	ParamTypeDuration = "duration"
 	ParamTypeJSON     = "json"
+
+	ParamTypeStringSlice   = "string-slice"
+	ParamTypeIntSlice      = "int-slice"
+	ParamTypeDurationSlice = "duration-slice"
+
+	// The string form of a ParamTypeStringMap param's value is a json object
+	// with string values, e.g. `{"env":"prod","team":"infra"}`
+	ParamTypeStringMap = "string-map"
 )
 
+// listParamTypes maps each of the list ParamTypes to the ParamType of its
+// elements. The string form of a list param's value is a json array of the
+// string forms of its elements, e.g. `["1s","5m"]` for a DurationSlice.
+var listParamTypes = map[string]string{
+	ParamTypeStringSlice:   ParamTypeString,
+	ParamTypeIntSlice:      ParamTypeInt,
+	ParamTypeDurationSlice: ParamTypeDuration,
+}
+
 // ParseFunc is a function that takes a string from the Source and converts it
 // into a value necessary to store in the sent pointer.
 type ParseFunc func(string, interface{}) error
@@ -30,9 +53,18 @@ var paramTypeParsers = map[string]ParseFunc{
 	ParamTypeString:   parseParamTypeString,
 	ParamTypeInt:      parseParamTypeInt,
 	ParamTypeInt64:    parseParamTypeInt64,
+	ParamTypeUint:     parseParamTypeUint,
+	ParamTypeUint64:   parseParamTypeUint64,
+	ParamTypeFloat64:  parseParamTypeFloat64,
 	ParamTypeBool:     parseParamTypeBool,
 	ParamTypeDuration: parseParamTypeDuration,
 	ParamTypeJSON:     parseParamTypeJSON,
+
+	ParamTypeStringSlice:   parseParamTypeStringSlice,
+	ParamTypeIntSlice:      parseParamTypeIntSlice,
+	ParamTypeDurationSlice: parseParamTypeDurationSlice,
+
+	ParamTypeStringMap: parseParamTypeStringMap,
 }
 
 func parseParamTypeString(val string, ptr interface{}) error {
@@ -40,29 +72,89 @@ func parseParamTypeString(val string, ptr interface{}) error {
 	return nil
 }
 
+// numError converts an error from one of strconv's number parsing functions
+// into one which is more readable for whoever is configuring the param
+func numError(err error, typ string) error {
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		return err
+	}
+	switch numErr.Err {
+	case strconv.ErrRange:
+		return fmt.Errorf("%q is out of range for type %s", numErr.Num, typ)
+	case strconv.ErrSyntax:
+		return fmt.Errorf("%q is not a valid %s", numErr.Num, typ)
+	}
+	return err
+}
+
 func parseParamTypeInt(val string, ptr interface{}) error {
-	vali, err := strconv.Atoi(val)
+	vali, err := strconv.ParseInt(val, 10, strconv.IntSize)
 	if err != nil {
-		return err
+		return numError(err, ParamTypeInt)
 	}
-	*(ptr.(*int)) = vali
+	*(ptr.(*int)) = int(vali)
 	return nil
 }
 
 func parseParamTypeInt64(val string, ptr interface{}) error {
 	vali, err := strconv.ParseInt(val, 10, 64)
 	if err != nil {
-		return err
+		return numError(err, ParamTypeInt64)
 	}
 	*(ptr.(*int64)) = vali
 	return nil
 }
 
+func parseParamTypeUint(val string, ptr interface{}) error {
+	valu, err := strconv.ParseUint(val, 10, strconv.IntSize)
+	if err != nil {
+		return numError(err, ParamTypeUint)
+	}
+	*(ptr.(*uint)) = uint(valu)
+	return nil
+}
+
+func parseParamTypeUint64(val string, ptr interface{}) error {
+	valu, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return numError(err, ParamTypeUint64)
+	}
+	*(ptr.(*uint64)) = valu
+	return nil
+}
+
+func parseParamTypeFloat64(val string, ptr interface{}) error {
+	valf, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return numError(err, ParamTypeFloat64)
+	}
+	*(ptr.(*float64)) = valf
+	return nil
+}
+
 func parseParamTypeBool(val string, ptr interface{}) error {
 	*(ptr.(*bool)) = (val != "" && val != "false")
 	return nil
 }
 
+// normalizeBool converts a boolean as it may be written in a config file, e.g.
+// "False", "0" or "no", into the "true" or "false" which parseParamTypeBool
+// expects, since that treats anything other than "false" as true
+func normalizeBool(val string) (string, error) {
+	switch strings.ToLower(val) {
+	case "y", "yes", "on":
+		return "true", nil
+	case "n", "no", "off":
+		return "false", nil
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a valid boolean", val)
+	}
+	return strconv.FormatBool(b), nil
+}
+
 func parseParamTypeDuration(val string, ptr interface{}) error {
 	d, err := time.ParseDuration(val)
 	if err != nil {
@@ -76,6 +168,80 @@ func parseParamTypeJSON(val string, ptr interface{}) error {
 	return json.Unmarshal([]byte(val), ptr)
 }
 
+// joinList returns the string form of a list param's value, given the string
+// forms of its elements. An empty list is returned as an empty string.
+func joinList(strs []string) string {
+	if len(strs) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(strs)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// joinListKeepEmpty is like joinList, but returns an empty list as "[]". It's
+// used by Sources for an empty list given explicitly, e.g. as an empty array in
+// a config file, which should still overwrite any default.
+func joinListKeepEmpty(strs []string) string {
+	if len(strs) == 0 {
+		return "[]"
+	}
+	return joinList(strs)
+}
+
+// splitList is the inverse of joinList
+func splitList(val string) ([]string, error) {
+	if val == "" {
+		return nil, nil
+	}
+	var strs []string
+	if err := json.Unmarshal([]byte(val), &strs); err != nil {
+		return nil, fmt.Errorf("malformed list %q: %v", val, err)
+	}
+	return strs, nil
+}
+
+func parseParamTypeStringSlice(val string, ptr interface{}) error {
+	strs, err := splitList(val)
+	if err != nil {
+		return err
+	}
+	*(ptr.(*[]string)) = strs
+	return nil
+}
+
+func parseParamTypeIntSlice(val string, ptr interface{}) error {
+	strs, err := splitList(val)
+	if err != nil {
+		return err
+	}
+	ints := make([]int, len(strs))
+	for i, str := range strs {
+		if err := parseParamTypeInt(str, &ints[i]); err != nil {
+			return err
+		}
+	}
+	*(ptr.(*[]int)) = ints
+	return nil
+}
+
+func parseParamTypeDurationSlice(val string, ptr interface{}) error {
+	strs, err := splitList(val)
+	if err != nil {
+		return err
+	}
+	durs := make([]time.Duration, len(strs))
+	for i, str := range strs {
+		if err := parseParamTypeDuration(str, &durs[i]); err != nil {
+			return err
+		}
+	}
+	*(ptr.(*[]time.Duration)) = durs
+	return nil
+}
+
 // JSONStringAsIs is a JSONStringFunc that just casts the json.RawMessage as a
 // string. It's used for non-quoted values in JSON like numbers and booleans.
 func JSONStringAsIs(j json.RawMessage) (string, error) {
@@ -93,6 +259,96 @@ func JSONStringUnmarshal(j json.RawMessage) (string, error) {
 	return str, nil
 }
 
+// joinMap returns the string form of a ParamTypeStringMap param's value. An
+// empty map is returned as an empty string.
+func joinMap(m map[string]string) string {
+	if len(m) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// joinMapKeepEmpty is like joinMap, but returns an empty map as "{}", see
+// joinListKeepEmpty
+func joinMapKeepEmpty(m map[string]string) string {
+	if len(m) == 0 {
+		return "{}"
+	}
+	return joinMap(m)
+}
+
+// splitKeyValue splits a "key=value" string into its key and value
+func splitKeyValue(kv string) (string, string, error) {
+	parts := strings.SplitN(kv, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("malformed key=value pair %q", kv)
+	}
+	return parts[0], parts[1], nil
+}
+
+func parseParamTypeStringMap(val string, ptr interface{}) error {
+	m := map[string]string{}
+	if val != "" {
+		if err := json.Unmarshal([]byte(val), &m); err != nil {
+			return fmt.Errorf("malformed map %q: %v", val, err)
+		}
+	}
+	*(ptr.(*map[string]string)) = m
+	return nil
+}
+
+// jsonStringList returns a JSONStringFunc which takes a json array and converts
+// it into the string form of a list param, using the given JSONStringFunc on
+// each element
+func jsonStringList(elemFn JSONStringFunc) JSONStringFunc {
+	return func(j json.RawMessage) (string, error) {
+		var elems []json.RawMessage
+		if err := json.Unmarshal(j, &elems); err != nil {
+			return "", err
+		}
+		strs := make([]string, len(elems))
+		for i, elem := range elems {
+			var err error
+			if strs[i], err = elemFn(elem); err != nil {
+				return "", err
+			}
+		}
+		return joinListKeepEmpty(strs), nil
+	}
+}
+
+// JSONStringAuto is a JSONStringFunc which uses JSONStringUnmarshal for json
+// strings and JSONStringAsIs for all other values. It's used for params whose
+// ParamType has no JSONStringFunc of its own, e.g. those defined using Define.
+func JSONStringAuto(j json.RawMessage) (string, error) {
+	if len(j) > 0 && j[0] == '"' {
+		return JSONStringUnmarshal(j)
+	}
+	return JSONStringAsIs(j)
+}
+
+// jsonStringMap is a JSONStringFunc which takes a json object and converts it
+// into the string form of a ParamTypeStringMap param. Values which aren't json
+// strings (e.g. numbers) are used as-is.
+func jsonStringMap(j json.RawMessage) (string, error) {
+	var jm map[string]json.RawMessage
+	if err := json.Unmarshal(j, &jm); err != nil {
+		return "", err
+	}
+	m := make(map[string]string, len(jm))
+	for k, v := range jm {
+		var err error
+		if m[k], err = JSONStringAuto(v); err != nil {
+			return "", err
+		}
+	}
+	return joinMapKeepEmpty(m), nil
+}
+
 // functions which will take json marshaled value for a ParamType and convert it
 // to a string which would be expected from a non-json source
 //
@@ -105,9 +361,28 @@ func JSONStringUnmarshal(j json.RawMessage) (string, error) {
 var paramTypeJSONStringers = map[string]JSONStringFunc{
 	ParamTypeString:   JSONStringUnmarshal,
 	ParamTypeInt:      JSONStringAsIs,
+	ParamTypeInt64:    JSONStringAsIs,
+	ParamTypeUint:     JSONStringAsIs,
+	ParamTypeUint64:   JSONStringAsIs,
+	ParamTypeFloat64:  JSONStringAsIs,
 	ParamTypeBool:     JSONStringAsIs,
 	ParamTypeDuration: JSONStringUnmarshal,
 	ParamTypeJSON:     JSONStringAsIs,
+
+	ParamTypeStringSlice:   jsonStringList(JSONStringUnmarshal),
+	ParamTypeIntSlice:      jsonStringList(JSONStringAsIs),
+	ParamTypeDurationSlice: jsonStringList(JSONStringUnmarshal),
+
+	ParamTypeStringMap: jsonStringMap,
+}
+
+// jsonStringer returns the JSONStringFunc for the given ParamType, falling back
+// to JSONStringAuto if it doesn't have one
+func jsonStringer(paramType string) JSONStringFunc {
+	if fn := paramTypeJSONStringers[paramType]; fn != nil {
+		return fn
+	}
+	return JSONStringAuto
 }
 
 var customParamTypeTypes = map[string]reflect.Type{}
@@ -0,0 +1,190 @@
+package lflag
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+// validator is called by ParseE with the param's pointer, once it has been
+// filled in
+type validator func(ptr interface{}) error
+
+// addValidator is a helper for ParamOptions which operate on the value of a
+// param's pointer. check is called on the param's type at definition time, and
+// should return an error if the ParamOption can't be used with it.
+func addValidator(check func(reflect.Type) error, fn func(reflect.Value) error) ParamOption {
+	return func(p *param) {
+		v := reflect.ValueOf(p.ptr).Elem()
+		if err := check(v.Type()); err != nil {
+			panic(fmt.Sprintf("lflag: param %q: %v", p.Name, err))
+		}
+		p.validators = append(p.validators, func(ptr interface{}) error {
+			return fn(reflect.ValueOf(ptr).Elem())
+		})
+	}
+}
+
+type numKind int
+
+const (
+	numKindNone numKind = iota
+	numKindInt
+	numKindUint
+	numKindFloat
+)
+
+func kindOfNum(k reflect.Kind) numKind {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return numKindInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return numKindUint
+	case reflect.Float32, reflect.Float64:
+		return numKindFloat
+	}
+	return numKindNone
+}
+
+// compareNums returns -1, 0, or 1 depending on if a is less than, equal to, or
+// greater than b. Both must be numeric.
+func compareNums(a, b reflect.Value) int {
+	ak, bk := kindOfNum(a.Kind()), kindOfNum(b.Kind())
+	switch {
+	case ak == numKindInt && bk == numKindInt:
+		return cmpResult(a.Int() < b.Int(), a.Int() > b.Int())
+	case ak == numKindUint && bk == numKindUint:
+		return cmpResult(a.Uint() < b.Uint(), a.Uint() > b.Uint())
+	case ak == numKindInt && bk == numKindUint && a.Int() < 0:
+		return -1
+	case ak == numKindUint && bk == numKindInt && b.Int() < 0:
+		return 1
+	case ak == numKindFloat || bk == numKindFloat:
+		af, bf := toFloat(a), toFloat(b)
+		return cmpResult(af < bf, af > bf)
+	}
+	// one is a non-negative int and the other a uint
+	au, bu := toUint(a), toUint(b)
+	return cmpResult(au < bu, au > bu)
+}
+
+func cmpResult(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch kindOfNum(v.Kind()) {
+	case numKindInt:
+		return float64(v.Int())
+	case numKindUint:
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+func toUint(v reflect.Value) uint64 {
+	if kindOfNum(v.Kind()) == numKindInt {
+		return uint64(v.Int())
+	}
+	return v.Uint()
+}
+
+func checkNumeric(bound interface{}) func(reflect.Type) error {
+	return func(typ reflect.Type) error {
+		if kindOfNum(typ.Kind()) == numKindNone {
+			return fmt.Errorf("type %s is not numeric", typ)
+		} else if kindOfNum(reflect.TypeOf(bound).Kind()) == numKindNone {
+			return fmt.Errorf("bound %v is not numeric", bound)
+		}
+		return nil
+	}
+}
+
+// Min is a ParamOption which requires the value of a numeric param (including
+// Duration) to be at least the given value, e.g. Min(1) or Min(time.Second).
+func Min(min interface{}) ParamOption {
+	return addValidator(checkNumeric(min), func(v reflect.Value) error {
+		if compareNums(v, reflect.ValueOf(min)) < 0 {
+			return fmt.Errorf("must be at least %v", min)
+		}
+		return nil
+	})
+}
+
+// Max is a ParamOption which requires the value of a numeric param (including
+// Duration) to be at most the given value, e.g. Max(100) or Max(time.Minute).
+func Max(max interface{}) ParamOption {
+	return addValidator(checkNumeric(max), func(v reflect.Value) error {
+		if compareNums(v, reflect.ValueOf(max)) > 0 {
+			return fmt.Errorf("must be at most %v", max)
+		}
+		return nil
+	})
+}
+
+// NonEmpty is a ParamOption which requires the value of a string, list, or map
+// param to not be empty.
+func NonEmpty() ParamOption {
+	check := func(typ reflect.Type) error {
+		switch typ.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map:
+			return nil
+		}
+		return fmt.Errorf("type %s can't be checked for emptiness", typ)
+	}
+	return addValidator(check, func(v reflect.Value) error {
+		if v.Len() == 0 {
+			return errors.New("must not be empty")
+		}
+		return nil
+	})
+}
+
+// Match is a ParamOption which requires the value of a string param to match
+// the given regular expression. It will panic if the expression can't be
+// compiled.
+func Match(expr string) ParamOption {
+	re := regexp.MustCompile(expr)
+	check := func(typ reflect.Type) error {
+		if typ.Kind() != reflect.String {
+			return fmt.Errorf("type %s is not a string", typ)
+		}
+		return nil
+	}
+	return addValidator(check, func(v reflect.Value) error {
+		if !re.MatchString(v.String()) {
+			return fmt.Errorf("%q does not match %q", v.String(), expr)
+		}
+		return nil
+	})
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Check is a ParamOption which calls the given function on the value of the
+// param, and if an error is returned then Parse will return it. fn must be a
+// function of the form func(T) error, where T is the type which the param's
+// pointer points to, e.g. func(int) error for an Int param.
+func Check(fn interface{}) ParamOption {
+	fnV := reflect.ValueOf(fn)
+	check := func(typ reflect.Type) error {
+		fnT := fnV.Type()
+		if fnT.Kind() != reflect.Func ||
+			fnT.NumIn() != 1 || fnT.In(0) != typ ||
+			fnT.NumOut() != 1 || fnT.Out(0) != errorType {
+			return fmt.Errorf("Check expects a func(%s) error, got %s", typ, fnT)
+		}
+		return nil
+	}
+	return addValidator(check, func(v reflect.Value) error {
+		err, _ := fnV.Call([]reflect.Value{v})[0].Interface().(error)
+		return err
+	})
+}
@@ -0,0 +1,89 @@
+package lflag
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"reflect"
+)
+
+// valueParamType returns the ParamType used for params defined using Var or
+// TextVar, which is the name of the type the receiver points to
+func valueParamType(rcv interface{}) string {
+	return reflect.Indirect(reflect.ValueOf(rcv)).Type().String()
+}
+
+func parseTextUnmarshaler(val string, ptr interface{}) error {
+	return ptr.(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+}
+
+func parseFlagValue(val string, ptr interface{}) error {
+	return ptr.(flag.Value).Set(val)
+}
+
+// Var defines a param whose value is stored in the given flag.Value, which is
+// updated using its Set method if a Source gives the param a value. The
+// default value is taken from its String method.
+//
+// If the flag.Value has an IsBoolFlag method which returns true, as for the
+// standard flag package, then the param acts like a Bool param.
+func (s *Set) Var(v flag.Value, name, usage string, opts ...ParamOption) {
+	p := Param{
+		ParamType: valueParamType(v),
+		Name:      name,
+		Default:   v.String(),
+		Usage:     usage,
+	}
+	parse := parseFlagValue
+	if bv, ok := v.(interface{ IsBoolFlag() bool }); ok && bv.IsBoolFlag() {
+		p.ParamType = ParamTypeBool
+		if p.Default == "false" {
+			p.Default = ""
+		}
+		// Sources only give "true" as a true value, see Source
+		parse = func(val string, ptr interface{}) error {
+			return parseFlagValue(fmt.Sprint(val == "true"), ptr)
+		}
+	}
+
+	opts = append([]ParamOption{withParseFunc(parse)}, opts...)
+	if ptr := s.newParam(p, v, opts...); ptr != v {
+		panic(fmt.Sprintf("param named %q already exists and differs from this new one", p.Name))
+	}
+}
+
+// Var calls Var on CommandLine
+func Var(v flag.Value, name, usage string, opts ...ParamOption) {
+	CommandLine.Var(v, name, usage, opts...)
+}
+
+// TextVar defines a param whose value is stored in the given
+// encoding.TextUnmarshaler, which is updated using its UnmarshalText method if
+// a Source gives the param a value. value is the default, which is marshaled
+// using MarshalText and unmarshaled into rcv immediately.
+//
+// If value cannot be marshaled or rcv cannot unmarshal it this will panic
+func (s *Set) TextVar(rcv encoding.TextUnmarshaler, name string, value encoding.TextMarshaler, usage string, opts ...ParamOption) {
+	def, err := value.MarshalText()
+	if err != nil {
+		panic(err)
+	} else if err := rcv.UnmarshalText(def); err != nil {
+		panic(err)
+	}
+
+	p := Param{
+		ParamType: valueParamType(rcv),
+		Name:      name,
+		Default:   string(def),
+		Usage:     usage,
+	}
+	opts = append([]ParamOption{withParseFunc(parseTextUnmarshaler)}, opts...)
+	if ptr := s.newParam(p, rcv, opts...); ptr != rcv {
+		panic(fmt.Sprintf("param named %q already exists and differs from this new one", p.Name))
+	}
+}
+
+// TextVar calls TextVar on CommandLine
+func TextVar(rcv encoding.TextUnmarshaler, name string, value encoding.TextMarshaler, usage string, opts ...ParamOption) {
+	CommandLine.TextVar(rcv, name, value, usage, opts...)
+}
@@ -0,0 +1,75 @@
+package lflag
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listValue is a flag.Value which appends to itself on each Set
+type listValue []string
+
+func (lv *listValue) String() string {
+	return strings.Join(*lv, ",")
+}
+
+func (lv *listValue) Set(s string) error {
+	*lv = append(*lv, s)
+	return nil
+}
+
+type boolValue bool
+
+func (bv *boolValue) String() string {
+	if *bv {
+		return "true"
+	}
+	return "false"
+}
+
+func (bv *boolValue) Set(s string) error {
+	*bv = s == "true"
+	return nil
+}
+
+func (bv *boolValue) IsBoolFlag() bool { return true }
+
+func TestVar(t *testing.T) {
+	s := NewSet()
+	lv := listValue{"a"}
+	lvDef := listValue{"a"}
+	bv := boolValue(false)
+	s.Var(&lv, "list", "Some list")
+	s.Var(&lvDef, "list-default", "Some list")
+	s.Var(&bv, "bool", "Some bool")
+
+	err := s.ParseE(SourceStub{"list": "b", "bool": "true"})
+	assert.NoError(t, err)
+	assert.Equal(t, listValue{"a", "b"}, lv)
+	assert.Equal(t, listValue{"a"}, lvDef)
+	assert.True(t, bool(bv))
+}
+
+func TestTextVar(t *testing.T) {
+	s := NewSet()
+	var ip, ipDef net.IP
+	s.TextVar(&ip, "ip", net.IPv4(127, 0, 0, 1), "Some ip")
+	s.TextVar(&ipDef, "ip-default", net.IPv4(127, 0, 0, 1), "Some ip")
+	ipDefine := DefineIn(s, "ip-define", net.IPv4(127, 0, 0, 1), "Some ip", nil)
+
+	err := s.ParseE(SourceStub{
+		"ip":        "10.0.0.1",
+		"ip-define": "10.0.0.2",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip.String())
+	assert.Equal(t, "127.0.0.1", ipDef.String())
+	assert.Equal(t, "10.0.0.2", ipDefine.String())
+
+	assert.Panics(t, func() {
+		DefineIn[time.Duration](NewSet(), "dur", 0, "Some duration", nil)
+	})
+}
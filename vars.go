@@ -7,7 +7,8 @@ import (
 
 // HelpPrefix may be set as a prefix to be printed out by sources which can
 // print out help strings (e.g. NewSourceCLI). If the string doesn't end in a
-// newline one will be added
+// newline one will be added. It is used by CommandLine if CommandLine's own
+// HelpPrefix field isn't set.
 var HelpPrefix string
 
 // Build variables which can be set during the go build command. E.g.:
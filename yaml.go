@@ -0,0 +1,269 @@
+package lflag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLStringFunc takes a YAML value and converts it into an appropriate string
+// that will be eventually sent to ParseFunc. YAMLStringScalar and
+// YAMLStringJSON are both YAMLStringFunc's.
+type YAMLStringFunc func(*yaml.Node) (string, error)
+
+// yamlResolve returns the node referred to by an alias (e.g. *foo), or the
+// given node if it isn't one
+func yamlResolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// YAMLStringScalar is a YAMLStringFunc which returns the value of a YAML scalar,
+// e.g. a string, number or boolean, as-is. Since YAML scalars needn't be quoted
+// this is the same as the value would be given on the command line.
+func YAMLStringScalar(n *yaml.Node) (string, error) {
+	n = yamlResolve(n)
+	if n.Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("line %d: expected a single value", n.Line)
+	}
+	return n.Value, nil
+}
+
+// YAMLStringJSON is a YAMLStringFunc which decodes the YAML value and encodes it
+// as JSON. It's used for ParamTypeJSON params.
+func YAMLStringJSON(n *yaml.Node) (string, error) {
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("line %d: %w", n.Line, err)
+	}
+	return string(b), nil
+}
+
+// YAMLStringAuto is a YAMLStringFunc which uses YAMLStringScalar for YAML
+// scalars and YAMLStringJSON for all other values. It's used for params whose
+// ParamType has no YAMLStringFunc of its own, e.g. those defined using Define.
+func YAMLStringAuto(n *yaml.Node) (string, error) {
+	if yamlResolve(n).Kind == yaml.ScalarNode {
+		return YAMLStringScalar(n)
+	}
+	return YAMLStringJSON(n)
+}
+
+// yamlStringBool is a YAMLStringFunc which takes a YAML boolean, or any of the
+// other forms accepted by normalizeBool (e.g. 0), and converts it into "true" or
+// "false"
+func yamlStringBool(n *yaml.Node) (string, error) {
+	var b bool
+	if err := n.Decode(&b); err == nil {
+		return strconv.FormatBool(b), nil
+	}
+	str, err := YAMLStringScalar(n)
+	if err != nil {
+		return "", err
+	} else if str, err = normalizeBool(str); err != nil {
+		return "", fmt.Errorf("line %d: %w", n.Line, err)
+	}
+	return str, nil
+}
+
+// yamlStringList returns a YAMLStringFunc which takes a YAML sequence and
+// converts it into the string form of a list param, using the given
+// YAMLStringFunc on each element
+func yamlStringList(elemFn YAMLStringFunc) YAMLStringFunc {
+	return func(n *yaml.Node) (string, error) {
+		n = yamlResolve(n)
+		if n.Kind != yaml.SequenceNode {
+			return "", fmt.Errorf("line %d: expected a list", n.Line)
+		}
+		strs := make([]string, len(n.Content))
+		for i, elem := range n.Content {
+			var err error
+			if strs[i], err = elemFn(elem); err != nil {
+				return "", err
+			}
+		}
+		return joinListKeepEmpty(strs), nil
+	}
+}
+
+// yamlStringMap is a YAMLStringFunc which takes a YAML mapping and converts it
+// into the string form of a ParamTypeStringMap param
+func yamlStringMap(n *yaml.Node) (string, error) {
+	n = yamlResolve(n)
+	if n.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("line %d: expected a mapping", n.Line)
+	}
+	m := make(map[string]string, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		var err error
+		if m[n.Content[i].Value], err = YAMLStringScalar(n.Content[i+1]); err != nil {
+			return "", err
+		}
+	}
+	return joinMapKeepEmpty(m), nil
+}
+
+// functions which will take the YAML value for a ParamType and convert it to a
+// string which would be expected from a non-yaml source, see
+// paramTypeJSONStringers
+var paramTypeYAMLStringers = map[string]YAMLStringFunc{
+	ParamTypeString:   YAMLStringScalar,
+	ParamTypeInt:      YAMLStringScalar,
+	ParamTypeInt64:    YAMLStringScalar,
+	ParamTypeUint:     YAMLStringScalar,
+	ParamTypeUint64:   YAMLStringScalar,
+	ParamTypeFloat64:  YAMLStringScalar,
+	ParamTypeBool:     yamlStringBool,
+	ParamTypeDuration: YAMLStringScalar,
+	ParamTypeJSON:     YAMLStringJSON,
+
+	ParamTypeStringSlice:   yamlStringList(YAMLStringScalar),
+	ParamTypeIntSlice:      yamlStringList(YAMLStringScalar),
+	ParamTypeDurationSlice: yamlStringList(YAMLStringScalar),
+
+	ParamTypeStringMap: yamlStringMap,
+}
+
+// yamlStringer returns the YAMLStringFunc for the given ParamType, falling back
+// to YAMLStringAuto if it doesn't have one
+func yamlStringer(paramType string) YAMLStringFunc {
+	if fn := paramTypeYAMLStringers[paramType]; fn != nil {
+		return fn
+	}
+	return YAMLStringAuto
+}
+
+// CustomParamTypeYAML sets the YAMLStringFunc used by NewSourceYAML for a
+// ParamType defined using CustomParamType. If not set YAMLStringAuto is used.
+func CustomParamTypeYAML(name string, yp YAMLStringFunc) {
+	if _, ok := customParamTypeTypes[name]; !ok {
+		panic("lflag: custom paramType not defined: " + name)
+	}
+	paramTypeYAMLStringers[name] = yp
+}
+
+type sourceYAML struct {
+	innerSrc     Source
+	testYAMLFile io.Reader // used as a fake yaml file in tests
+}
+
+// NewSourceYAML wraps an existing Source and adds support for reading a yaml
+// file to source parameter values. The values coming from the inner Source will
+// overwrite any which are found in the yaml file.
+//
+// The file is named by the config-yaml-file param, which may be given multiple
+// times, e.g. a base file followed by an environment's overlay, with values in
+// later files overwriting earlier ones. It may also name a directory, in which
+// case the *.yaml and *.yml files within it are read in lexical order.
+//
+// The file may include others by giving their paths, relative to its own
+// directory, under the "$include" key. Those are read before the file itself,
+// so that it overwrites them.
+//
+// Keys within a nested mapping are prefixed by the mapping's key, like
+// Prefixed, so "addr" within "db" is the value of the "db-addr" param. Flat keys
+// like "db-addr" may also be used, but it's an error for a param to be given by
+// both.
+//
+// List params (e.g. StringSlice) are given as yaml sequences in the file, and
+// map params (e.g. StringMap) as yaml mappings.
+func NewSourceYAML(inner Source) Source {
+	return sourceYAML{innerSrc: inner}
+}
+
+func (sy sourceYAML) Parse(pp []Param) (map[string]string, error) {
+	vals, _, err := sy.parseSet(detachedState(), pp)
+	if err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+func (sy sourceYAML) String() string {
+	return "yaml"
+}
+
+func (sy sourceYAML) innerSources() []Source {
+	return []Source{sy.innerSrc}
+}
+
+func (sy sourceYAML) parseSet(st *parseState, pp []Param) (map[string]string, map[string]ParamOrigin, error) {
+	return configFile{
+		src:      sy,
+		inner:    sy.innerSrc,
+		name:     "config-yaml-file",
+		kind:     "yaml",
+		exts:     []string{".yaml", ".yml"},
+		testFile: sy.testYAMLFile,
+		parse:    sy.parseFile,
+	}.parseSet(st, pp)
+}
+
+// parseFile decodes the given yaml file and returns the values found in it for
+// the given params
+func (sy sourceYAML) parseFile(r io.Reader, pp []Param) (map[string]string, map[string]string, []string, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); errors.Is(err, io.EOF) {
+		// an empty file has no values in it
+		return nil, nil, nil, nil
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// an empty document, e.g. just "---", is the same as an empty file
+	if len(doc.Content) == 0 || yamlResolve(doc.Content[0]).Tag == "!!null" {
+		return nil, nil, nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, nil, fmt.Errorf("line %d: expected a mapping at the top level", root.Line)
+	}
+
+	tree := yamlTable(root)
+	var inc interface{}
+	if n, ok := tree[includeKey]; ok {
+		if err := n.(*yaml.Node).Decode(&inc); err != nil {
+			return nil, nil, nil, err
+		}
+		delete(tree, includeKey)
+	}
+	includes, err := configIncludes(inc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// now transform the yaml mapping into a map[string]string using the yaml
+	// stringers, flattening any nested mappings
+	toString := func(p Param, v interface{}) (string, error) {
+		return yamlStringer(p.ParamType)(v.(*yaml.Node))
+	}
+	vals, keys, err := flattenConfig(tree, pp, toString, yamlTable)
+	return vals, keys, includes, err
+}
+
+// yamlTable is used as the table argument of flattenConfig, returning the
+// contents of a yaml mapping. We treat null and unset as the same thing, so null
+// values are left out.
+func yamlTable(v interface{}) map[string]interface{} {
+	n := yamlResolve(v.(*yaml.Node))
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	m := make(map[string]interface{}, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if yamlResolve(n.Content[i+1]).Tag != "!!null" {
+			m[n.Content[i].Value] = n.Content[i+1]
+		}
+	}
+	return m
+}
@@ -0,0 +1,126 @@
+package lflag
+
+import (
+	"bytes"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceYAML(t *T) {
+	pp := []Param{
+		{ParamType: ParamTypeString, Name: "str"},
+		{ParamType: ParamTypeString, Name: "str2"},
+		{ParamType: ParamTypeString, Name: "null"},
+		{ParamType: ParamTypeInt, Name: "int"},
+		{ParamType: ParamTypeInt64, Name: "int64"},
+		{ParamType: ParamTypeUint64, Name: "uint64"},
+		{ParamType: ParamTypeFloat64, Name: "float64"},
+		{ParamType: ParamTypeBool, Name: "bool"},
+		{ParamType: ParamTypeDuration, Name: "dur"},
+		{ParamType: ParamTypeJSON, Name: "json"},
+		{ParamType: ParamTypeStringSlice, Name: "strs"},
+		{ParamType: ParamTypeIntSlice, Name: "ints"},
+		{ParamType: ParamTypeDurationSlice, Name: "durs"},
+		{ParamType: ParamTypeStringMap, Name: "labels"},
+		{ParamType: "custom-thing", Name: "custom"},
+	}
+
+	ts := SourceStub{
+		"str2": "bar", // this should overwrite the one in the yamlFile
+	}
+
+	yamlFile := bytes.NewBufferString(`
+str: "foo\nsomething"
+str2: broken
+null: ~
+int: 1
+int64: -9000000000
+uint64: 18446744073709551615
+float64: 1.5
+bool: true
+dur: 30s
+json:
+  foo: bar
+  n: [1, 2]
+strs: [a, b]
+ints:
+  - 1
+  - &two 2
+durs: []
+labels: {env: prod, shard: *two}
+custom: {a: 1}
+`)
+
+	m, err := sourceYAML{innerSrc: ts, testYAMLFile: yamlFile}.Parse(pp)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"str":     "foo\nsomething",
+		"str2":    "bar",
+		"int":     "1",
+		"int64":   "-9000000000",
+		"uint64":  "18446744073709551615",
+		"float64": "1.5",
+		"bool":    "true",
+		"dur":     "30s",
+		"json":    `{"foo":"bar","n":[1,2]}`,
+		"strs":    `["a","b"]`,
+		"ints":    `["1","2"]`,
+		"durs":    "[]",
+		"labels":  `{"env":"prod","shard":"2"}`,
+		"custom":  `{"a":1}`,
+	}, m)
+
+	_, err = sourceYAML{innerSrc: SourceStub{}, testYAMLFile: bytes.NewBufferString(`
+str: [a, b]
+strs: a
+`)}.Parse(pp)
+	assert.EqualError(t, err, `2 configuration errors:
+	parameter "str" (from yaml): line 2: expected a single value
+	parameter "strs" (from yaml): line 3: expected a list`)
+
+	for _, empty := range []string{"", "---\n", "# nothing here\n", "~\n", "--- null\n"} {
+		m, err = sourceYAML{innerSrc: SourceStub{}, testYAMLFile: bytes.NewBufferString(empty)}.Parse(pp)
+		assert.NoError(t, err, empty)
+		assert.Empty(t, m, empty)
+	}
+
+	_, err = sourceYAML{innerSrc: SourceStub{}, testYAMLFile: bytes.NewBufferString("---\n[a]\n")}.Parse(pp)
+	assert.EqualError(t, err, "yaml: line 2: expected a mapping at the top level")
+}
+
+func TestSourceYAMLBool(t *T) {
+	pp := []Param{{ParamType: ParamTypeBool, Name: "verbose"}}
+	for in, exp := range map[string]string{
+		"true": "true", "False": "false", "FALSE": "false", "no": "false",
+		"off": "false", "0": "false", "1": "true", "yes": "true",
+	} {
+		m, err := sourceYAML{innerSrc: SourceStub{}, testYAMLFile: bytes.NewBufferString(
+			"verbose: " + in,
+		)}.Parse(pp)
+		assert.NoError(t, err, in)
+		assert.Equal(t, map[string]string{"verbose": exp}, m, in)
+	}
+
+	_, err := sourceYAML{innerSrc: SourceStub{}, testYAMLFile: bytes.NewBufferString(
+		"verbose: maybe",
+	)}.Parse(pp)
+	assert.EqualError(t, err, `parameter "verbose" (from yaml): line 1: "maybe" is not a valid boolean`)
+}
+
+func TestSourceYAMLNested(t *T) {
+	pp := []Param{
+		{ParamType: ParamTypeString, Name: "db-addr"},
+		{ParamType: ParamTypeStringMap, Name: "db-opts"},
+	}
+	m, err := sourceYAML{innerSrc: SourceStub{}, testYAMLFile: bytes.NewBufferString(`
+db:
+  addr: localhost:5432
+  opts: {sslmode: disable}
+`)}.Parse(pp)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"db-addr": "localhost:5432",
+		"db-opts": `{"sslmode":"disable"}`,
+	}, m)
+}